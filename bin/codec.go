@@ -19,6 +19,11 @@ const (
 
 // RobloxCodec implements Decoder and Encoder to emulate Roblox's internal
 // codec as closely as possible.
+//
+// Values that the format cannot represent are encoded as the nearest type
+// that it can. In particular, a rbxfile.ValueContentData is encoded as a
+// shared string of its Data, or as a string of its Hash if Data is nil, and
+// is decoded as that type.
 type RobloxCodec struct {
 	// API can be set to yield a more correct encoding or decoding by
 	// providing information about each class. If API is nil, the codec will
@@ -42,20 +47,80 @@ type RobloxCodec struct {
 	// generally preferred to set ExcludeInvalidAPI to false, so that false
 	// negatives do not lead to lost data.
 	ExcludeInvalidAPI bool
+
+	// References is used to generate references for decoded instances, which
+	// are not stored by the format. It is also set as the ReferenceGenerator
+	// of the decoded Root. If nil, then rbxfile.CryptoReferences is used.
+	References rbxfile.ReferenceGenerator
+
+	// Lazy determines whether property values are decoded on demand. When
+	// true, Decode does not populate the properties of decoded instances.
+	// Instead, each instance is given a rbxfile.PropertyLoader, which decodes
+	// a property from the corresponding ChunkProperty when the property is
+	// first accessed. The chunks of the FormatModel are retained until every
+	// property has been loaded. If the model was read with
+	// FormatModel.LazyProperties set, then the values of a chunk are not
+	// decoded at all until one of them is accessed.
+	//
+	// Because values are decoded after Decode returns, errors in the values
+	// cannot be returned. A property whose value cannot be decoded, or
+	// exceeds the limits of the model, is absent instead, and the error is
+	// appended to model.Warnings when the property is loaded. Only the first
+	// error of each chunk is recorded.
+	Lazy bool
 }
 
+// Decode decodes a FormatModel into a Root. If model.Recover is true, then a
+// chunk that cannot be decoded is discarded and recorded in model.Damaged
+// instead of producing an error, and instances whose parent was lost are
+// placed at the top level of the Root.
+//
+// Decode honors model.Limits, except for MaxChunkSize and MaxTotalBytes,
+// which apply only to reading the format. A rbxfile.ErrLimit is returned if a
+// limit is exceeded, regardless of model.Recover.
 func (c RobloxCodec) Decode(model *FormatModel) (root *rbxfile.Root, err error) {
+	root, _, err = c.decode(model)
+	return root, err
+}
+
+// decode implements Decode, additionally returning a map of IDs to the
+// decoded instances.
+func (c RobloxCodec) decode(model *FormatModel) (root *rbxfile.Root, instLookup map[int32]*rbxfile.Instance, err error) {
 	if model == nil {
-		return nil, fmt.Errorf("FormatModel is nil")
+		return nil, nil, fmt.Errorf("FormatModel is nil")
 	}
 	model.Warnings = model.Warnings[:0]
+	model.progress.Instances = 0
+
+	limits := model.Limits
+	if err = limits.Check(rbxfile.LimitInstances, int64(model.InstanceCount)); err != nil {
+		return nil, nil, err
+	}
+
+	root = &rbxfile.Root{ReferenceGenerator: c.References}
 
-	root = new(rbxfile.Root)
+	// The counts in the header are not trusted for allocation; each group and
+	// instance must actually be present in a chunk.
+	var typeCount, instCount int
+	for _, chunk := range model.Chunks {
+		if chunk, ok := chunk.(*ChunkInstance); ok {
+			typeCount++
+			instCount += len(chunk.InstanceIDs)
+		}
+	}
+	if uint32(typeCount) > model.TypeCount {
+		typeCount = int(model.TypeCount)
+	}
+	if uint32(instCount) > model.InstanceCount {
+		instCount = int(model.InstanceCount)
+	}
 
-	groupLookup := make(map[int32]*ChunkInstance, model.TypeCount)
-	instLookup := make(map[int32]*rbxfile.Instance, model.InstanceCount+1)
+	groupLookup := make(map[int32]*ChunkInstance, typeCount)
+	instLookup = make(map[int32]*rbxfile.Instance, instCount+1)
 	instLookup[-1] = nil
 
+	var propCount int64
+
 	propTypes := map[string]map[string]rbxapi.Type{}
 
 	var sharedStrings []SharedString
@@ -63,6 +128,9 @@ func (c RobloxCodec) Decode(model *FormatModel) (root *rbxfile.Root, err error)
 	// Caches an enum name to a set of enum item values.
 	enumCache := map[string]enumItems{}
 
+	// Property loaders of each instance group, when decoding lazily.
+	lazyGroups := map[int32]*lazyGroup{}
+
 	var chunkType string
 	var chunkNum int
 
@@ -76,10 +144,16 @@ func (c RobloxCodec) Decode(model *FormatModel) (root *rbxfile.Root, err error)
 
 loop:
 	for ic, chunk := range model.Chunks {
+		if err = model.checkContext(); err != nil {
+			return nil, nil, err
+		}
 		chunkNum = ic
 		switch chunk := chunk.(type) {
 		case *ChunkInstance:
 			chunkType = "instance"
+			if err = limits.Check(rbxfile.LimitStringLength, int64(len(chunk.ClassName))); err != nil {
+				return nil, nil, err
+			}
 			if chunk.TypeID < 0 || uint32(chunk.TypeID) >= model.TypeCount {
 				err = fmt.Errorf("type index out of bounds: %d", model.TypeCount)
 				goto chunkErr
@@ -140,11 +214,14 @@ loop:
 				}
 				// No error if InstanceCount > actual count.
 
-				inst := rbxfile.NewInstance(chunk.ClassName, nil)
 				if _, ok := instLookup[ref]; ok {
 					err = fmt.Errorf("duplicate id: %d", ref)
 					goto chunkErr
 				}
+				inst := rbxfile.NewInstance(chunk.ClassName, nil)
+				if c.References != nil {
+					inst.Reference = c.References.GenerateReference()
+				}
 
 				if chunk.IsService && chunk.GetService[i] == 1 {
 					inst.IsService = true
@@ -158,9 +235,14 @@ loop:
 				goto chunkErr
 			}
 			groupLookup[chunk.TypeID] = chunk
+			model.progress.Instances += len(chunk.InstanceIDs)
+			model.report()
 
 		case *ChunkProperty:
 			chunkType = "property"
+			if err = limits.Check(rbxfile.LimitStringLength, int64(len(chunk.PropertyName))); err != nil {
+				return nil, nil, err
+			}
 			if chunk.TypeID < 0 || uint32(chunk.TypeID) >= model.TypeCount {
 				err = fmt.Errorf("type index out of bounds: %d", model.TypeCount)
 				goto chunkErr
@@ -173,9 +255,19 @@ loop:
 				continue
 			}
 
-			if len(chunk.Properties) != len(instChunk.InstanceIDs) {
-				err = fmt.Errorf("length of properties array (%d) does not equal length of type array (%d)", len(chunk.Properties), len(instChunk.InstanceIDs))
-				goto chunkErr
+			if !c.Lazy {
+				if err = chunk.LoadProperties(); err != nil {
+					goto chunkErr
+				}
+			}
+			if chunk.raw == nil {
+				if column := chunk.values(); column == nil {
+					err = &ErrInvalidType{Chunk: chunk}
+					goto chunkErr
+				} else if column.Len() != len(instChunk.InstanceIDs) {
+					err = fmt.Errorf("length of properties array (%d) does not equal length of type array (%d)", column.Len(), len(instChunk.InstanceIDs))
+					goto chunkErr
+				}
 			}
 
 			var propType rbxapi.Type
@@ -189,23 +281,43 @@ loop:
 				}
 			}
 
-			for i, bvalue := range chunk.Properties {
-				// If the value type is an enum, then verify that the value is
-				// correct for the enum.
-				if c.API != nil && bvalue.Type() == TypeToken {
-					if items, ok := enumCache[propType.GetName()]; ok {
-						token := bvalue.(*ValueToken)
-						if !items.values[int(*token)] {
-							// If it isn't valid, then use the first value of
-							// the enum instead.
-							v := items.first
-							*token = ValueToken(v)
-						}
+			propCount += int64(len(instChunk.InstanceIDs))
+			if err = limits.Check(rbxfile.LimitProperties, propCount); err != nil {
+				return nil, nil, err
+			}
+
+			col := &propColumn{
+				chunk:         chunk,
+				propType:      propType,
+				sharedStrings: sharedStrings,
+			}
+			if c.API != nil && propType != nil {
+				if items, ok := enumCache[propType.GetName()]; ok {
+					col.enum = &items
+				}
+			}
+
+			if c.Lazy {
+				group, ok := lazyGroups[chunk.TypeID]
+				if !ok {
+					group = &lazyGroup{
+						model:      model,
+						limits:     limits,
+						instLookup: instLookup,
+						count:      len(instChunk.InstanceIDs),
 					}
+					lazyGroups[chunk.TypeID] = group
 				}
+				group.add(col, ic)
+				continue
+			}
 
-				inst := instLookup[instChunk.InstanceIDs[i]]
-				inst.Properties[chunk.PropertyName] = decodeValue(propType, instLookup, sharedStrings, bvalue)
+			for i, id := range instChunk.InstanceIDs {
+				var value rbxfile.Value
+				if value, err = col.value(limits, instLookup, i); err != nil {
+					return nil, nil, err
+				}
+				instLookup[id].Properties[chunk.PropertyName] = value
 			}
 
 		case *ChunkParent:
@@ -255,11 +367,22 @@ loop:
 				root.Metadata = make(map[string]string, len(chunk.Values))
 			}
 			for _, pair := range chunk.Values {
+				if err = limits.Check(rbxfile.LimitStringLength, int64(len(pair[0]))); err != nil {
+					return nil, nil, err
+				}
+				if err = limits.Check(rbxfile.LimitStringLength, int64(len(pair[1]))); err != nil {
+					return nil, nil, err
+				}
 				root.Metadata[pair[0]] = pair[1]
 			}
 
 		case *ChunkSharedStrings:
 			chunkType = "sharedstring"
+			for _, value := range chunk.Values {
+				if err = limits.Check(rbxfile.LimitStringLength, int64(len(value.Value))); err != nil {
+					return nil, nil, err
+				}
+			}
 			// TODO: How are multiple chunks handled (overwrite or append)?
 			sharedStrings = chunk.Values
 
@@ -267,13 +390,196 @@ loop:
 			chunkType = "end"
 			break loop
 		}
+		continue
+
+	chunkErr:
+		if !model.Recover {
+			err = fmt.Errorf("%s chunk (#%d): %s", chunkType, chunkNum, err)
+			return nil, nil, err
+		}
+		// Discard the chunk and carry on with whatever remains.
+		model.Damaged = append(model.Damaged, DamagedChunk{
+			Offset: -1,
+			Sig:    chunk.Signature(),
+			Chunk:  chunk,
+			Err:    err,
+		})
+		addWarn("%s", err)
+		err = nil
 	}
 
+	for typeID, group := range lazyGroups {
+		for i, id := range groupLookup[typeID].InstanceIDs {
+			instLookup[id].SetPropertyLoader(group, i)
+		}
+	}
+
+	if model.Recover {
+		// Instances whose parent was lost are placed at the top level, in
+		// order of their ID.
+		top := make(map[*rbxfile.Instance]bool, len(root.Instances))
+		for _, inst := range root.Instances {
+			top[inst] = true
+		}
+		ids := make([]int, 0, len(instLookup))
+		for id, inst := range instLookup {
+			if inst != nil && inst.Parent() == nil && !top[inst] {
+				ids = append(ids, int(id))
+			}
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			root.Instances = append(root.Instances, instLookup[int32(id)])
+		}
+	}
+
+	if err = checkDepth(limits, root.Instances); err != nil {
+		return nil, nil, err
+	}
+	root.FixTree()
+
 	return
+}
 
-chunkErr:
-	err = fmt.Errorf("%s chunk (#%d): %s", chunkType, chunkNum, err)
-	return nil, err
+// propColumn holds the values of a ChunkProperty, along with the information
+// needed to decode them.
+type propColumn struct {
+	chunk         *ChunkProperty
+	column        Column
+	propType      rbxapi.Type
+	enum          *enumItems
+	sharedStrings []SharedString
+}
+
+// value decodes the value of the column at index i. An error is returned if
+// the value exceeds limits.
+func (col *propColumn) value(limits rbxfile.Limits, instLookup map[int32]*rbxfile.Instance, i int) (value rbxfile.Value, err error) {
+	if col.column == nil {
+		col.column = col.chunk.values()
+	}
+	bvalue := col.column.Value(i)
+	if s, ok := bvalue.(*ValueString); ok {
+		if err = limits.Check(rbxfile.LimitStringLength, int64(len(*s))); err != nil {
+			return nil, err
+		}
+	}
+
+	// If the value type is an enum, then verify that the value is correct for
+	// the enum.
+	if col.enum != nil && bvalue.Type() == TypeToken {
+		token := bvalue.(*ValueToken)
+		if !col.enum.values[int(*token)] {
+			// If it isn't valid, then use the first value of the enum
+			// instead.
+			*token = ValueToken(col.enum.first)
+		}
+	}
+
+	return decodeValue(col.propType, instLookup, col.sharedStrings, bvalue), nil
+}
+
+// lazyGroup implements rbxfile.PropertyLoader for the instances of an
+// instance group, where the index of an instance is its position in the
+// group. Values are decoded from the property columns of the group when they
+// are loaded.
+type lazyGroup struct {
+	model      *FormatModel
+	limits     rbxfile.Limits
+	instLookup map[int32]*rbxfile.Instance
+	count      int
+	names      []string
+	columns    []*propColumn
+	// nums is the index of the chunk of each column within the model, and
+	// failed indicates whether an error has been recorded for the column.
+	nums   []int
+	failed []bool
+}
+
+// add adds a column, read from chunk number num of the model, to the group,
+// replacing any column of the same name.
+func (g *lazyGroup) add(col *propColumn, num int) {
+	for i, name := range g.names {
+		if name == col.chunk.PropertyName {
+			g.columns[i] = col
+			g.nums[i] = num
+			g.failed[i] = false
+			return
+		}
+	}
+	g.names = append(g.names, col.chunk.PropertyName)
+	g.columns = append(g.columns, col)
+	g.nums = append(g.nums, num)
+	g.failed = append(g.failed, false)
+}
+
+// warn appends err to the warnings of the model, unless an error has already
+// been recorded for column p.
+func (g *lazyGroup) warn(p int, err error) {
+	if g.failed[p] {
+		return
+	}
+	g.failed[p] = true
+	g.model.Warnings = append(g.model.Warnings, fmt.Errorf("property chunk (#%d): %w", g.nums[p], err))
+}
+
+func (g *lazyGroup) PropertyNames() []string {
+	return g.names
+}
+
+func (g *lazyGroup) LoadProperty(i, p int) rbxfile.Value {
+	col := g.columns[p]
+	if err := col.chunk.LoadProperties(); err != nil {
+		g.warn(p, err)
+		return nil
+	}
+	if col.column == nil {
+		col.column = col.chunk.values()
+	}
+	if col.column == nil {
+		g.warn(p, &ErrInvalidType{Chunk: col.chunk})
+		return nil
+	}
+	if col.column.Len() != g.count {
+		g.warn(p, fmt.Errorf("length of properties array (%d) does not equal length of type array (%d)", col.column.Len(), g.count))
+		return nil
+	}
+	if i < 0 || i >= g.count {
+		return nil
+	}
+	value, err := col.value(g.limits, g.instLookup, i)
+	if err != nil {
+		g.warn(p, err)
+		return nil
+	}
+	return value
+}
+
+// checkDepth returns an error if the depth of the tree formed by instances
+// exceeds the limit.
+func checkDepth(limits rbxfile.Limits, instances []*rbxfile.Instance) error {
+	if limits.MaxDepth <= 0 {
+		return nil
+	}
+
+	type node struct {
+		inst  *rbxfile.Instance
+		depth int
+	}
+	stack := make([]node, 0, len(instances))
+	for _, inst := range instances {
+		stack = append(stack, node{inst, 1})
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if err := limits.Check(rbxfile.LimitDepth, int64(n.depth)); err != nil {
+			return err
+		}
+		for _, child := range n.inst.Children {
+			stack = append(stack, node{child, n.depth + 1})
+		}
+	}
+	return nil
 }
 
 // Decode a bin.value to a rbxfile.Value based on a given value type.
@@ -492,15 +798,22 @@ type sharedEntry struct {
 type sharedMap map[[16]byte]sharedEntry
 
 func (c RobloxCodec) Encode(root *rbxfile.Root) (model *FormatModel, err error) {
+	model, _, err = c.encode(root)
+	return model, err
+}
+
+// encode implements Encode, additionally returning the list of encoded
+// instances, where the index of an instance is its ID.
+func (c RobloxCodec) encode(root *rbxfile.Root) (model *FormatModel, instList []*rbxfile.Instance, err error) {
 	if root == nil {
-		return nil, errors.New("Root is nil")
+		return nil, nil, errors.New("Root is nil")
 	}
 
 	model = new(FormatModel)
 
 	// A list of instances in the tree. The index serves as the instance's
 	// reference number.
-	instList := make([]*rbxfile.Instance, 0)
+	instList = make([]*rbxfile.Instance, 0)
 
 	// A map used to ensure that an instance is counted only once. Also used
 	// to link ValueReferences.
@@ -529,6 +842,8 @@ func (c RobloxCodec) Encode(root *rbxfile.Root) (model *FormatModel, err error)
 			}
 		}
 
+		inst.LoadProperties()
+
 		// Reference number should match position in list.
 		refs[inst] = len(instList)
 		instList = append(instList, inst)
@@ -700,7 +1015,6 @@ func (c RobloxCodec) Encode(root *rbxfile.Root) (model *FormatModel, err error)
 					TypeID:       instChunk.TypeID,
 					PropertyName: name,
 					DataType:     dataType,
-					Properties:   make([]Value, len(instChunk.InstanceIDs)),
 				}
 			}
 		}
@@ -743,8 +1057,13 @@ func (c RobloxCodec) Encode(root *rbxfile.Root) (model *FormatModel, err error)
 			}
 		}
 
-		// Set the values for each property chunk.
+		// Set the values for each property chunk. The column is created
+		// only now, since DataType may have been changed above.
 		for name, propChunk := range propChunkMap {
+			propChunk.Column = NewColumn(propChunk.DataType, len(instChunk.InstanceIDs))
+			if propChunk.Column == nil {
+				return nil, nil, &ErrInvalidType{Chunk: propChunk}
+			}
 			for i, ref := range instChunk.InstanceIDs {
 				inst := instList[ref]
 
@@ -775,7 +1094,9 @@ func (c RobloxCodec) Encode(root *rbxfile.Root) (model *FormatModel, err error)
 					}
 				}
 
-				propChunk.Properties[i] = bvalue
+				if err = propChunk.Column.SetValue(i, bvalue); err != nil {
+					return nil, nil, err
+				}
 			}
 		}
 
@@ -948,6 +1269,17 @@ func encodeValue(
 		copy(v, value)
 		bvalue = (*ValueString)(&v)
 
+	case rbxfile.ValueContentData:
+		// The format has no embedded content, so the data is encoded as a
+		// shared string, losing the hash. Content without data is encoded as
+		// its hash. See RobloxCodec.
+		if value.Data == nil {
+			v := []byte(value.Hash)
+			bvalue = (*ValueString)(&v)
+			break
+		}
+		bvalue = encodeValue(refs, sharedStrings, rbxfile.ValueSharedString(value.Data))
+
 	case rbxfile.ValueBool:
 		bvalue = (*ValueBool)(&value)
 
@@ -0,0 +1,293 @@
+package bin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/xml"
+)
+
+func TestCodecReferences(t *testing.T) {
+	data := encodeRecoverTest(t, recoverTestRoot())
+
+	encode := func() []byte {
+		codec := RobloxCodec{References: &rbxfile.SequentialReferences{}}
+		root, err := Serializer{Decoder: codec, Encoder: codec}.Deserialize(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("deserialize: %s", err)
+		}
+		if _, ok := root.ReferenceGenerator.(*rbxfile.SequentialReferences); !ok {
+			t.Error("expected root to use codec's generator")
+		}
+		var buf bytes.Buffer
+		if err := xml.Serialize(&buf, nil, root); err != nil {
+			t.Fatalf("serialize: %s", err)
+		}
+		return buf.Bytes()
+	}
+	a, b := encode(), encode()
+	if !bytes.Equal(a, b) {
+		t.Error("expected identical output")
+	}
+	if !bytes.Contains(a, []byte(`referent="RBX0"`)) {
+		t.Error("expected sequential referents")
+	}
+}
+
+func TestCodecLazy(t *testing.T) {
+	data := encodeRecoverTest(t, recoverTestRoot())
+	eager, err := NewSerializer(nil, nil).Deserialize(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+
+	model := &FormatModel{LazyProperties: true}
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	for _, chunk := range model.Chunks {
+		if chunk, ok := chunk.(*ChunkProperty); ok && chunk.Column != nil {
+			t.Errorf("%s: expected undecoded properties", chunk.PropertyName)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := model.WriteTo(&buf); err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("expected undecoded chunks to be written unchanged")
+	}
+
+	root, err := RobloxCodec{Lazy: true}.Decode(model)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	part := root.Instances[0].Children[0]
+	if len(part.Properties) != 0 {
+		t.Error("expected properties to be unloaded")
+	}
+	if part.Get("Anchored") != rbxfile.ValueBool(true) {
+		t.Error("expected Anchored to be loaded")
+	}
+	var name *ChunkProperty
+	types := map[int32]string{}
+	for _, chunk := range model.Chunks {
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			types[chunk.TypeID] = chunk.ClassName
+		case *ChunkProperty:
+			if chunk.PropertyName == "Name" && types[chunk.TypeID] == "Folder" {
+				name = chunk
+			}
+		}
+	}
+	if name == nil || name.Column != nil {
+		t.Error("expected only accessed chunks to be decoded")
+	}
+
+	encode := func(root *rbxfile.Root) []byte {
+		var buf bytes.Buffer
+		if err := NewSerializer(nil, nil).Serialize(&buf, root); err != nil {
+			t.Fatalf("serialize: %s", err)
+		}
+		return buf.Bytes()
+	}
+	if !bytes.Equal(encode(root), encode(eager)) {
+		t.Error("expected lazy root to encode like eager root")
+	}
+}
+
+func TestDeserializeContext(t *testing.T) {
+	data := encodeRecoverTest(t, recoverTestRoot())
+	var xmlBuf bytes.Buffer
+	if err := xml.Serialize(&xmlBuf, nil, recoverTestRoot()); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSerializer(nil, nil)
+	model := new(FormatModel)
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	chunks := len(model.Chunks)
+
+	var last rbxfile.Progress
+	if _, err := s.DeserializeContext(context.Background(), bytes.NewReader(data), func(p rbxfile.Progress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if last.Bytes != int64(len(data)) || last.Chunks != chunks || last.Instances != 3 {
+		t.Errorf("unexpected progress %+v", last)
+	}
+
+	last = rbxfile.Progress{}
+	if _, err := s.DeserializeContext(context.Background(), bytes.NewReader(xmlBuf.Bytes()), func(p rbxfile.Progress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("deserialize xml: %s", err)
+	}
+	if last.Bytes != int64(xmlBuf.Len()) || last.Instances != 3 {
+		t.Errorf("unexpected xml progress %+v", last)
+	}
+
+	for _, input := range [][]byte{data, xmlBuf.Bytes()} {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := s.DeserializeContext(ctx, bytes.NewReader(input), func(p rbxfile.Progress) {
+			cancel()
+		})
+		if err != context.Canceled {
+			t.Errorf("expected cancellation, got %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	last = rbxfile.Progress{}
+	if err := s.SerializeContext(context.Background(), &buf, recoverTestRoot(), func(p rbxfile.Progress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	if last.Bytes != int64(buf.Len()) || last.Chunks != chunks {
+		t.Errorf("unexpected progress %+v", last)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.SerializeContext(ctx, &buf, recoverTestRoot(), func(p rbxfile.Progress) {
+		cancel()
+	}); err != context.Canceled {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
+
+func TestCodecLazyWarnings(t *testing.T) {
+	root := recoverTestRoot()
+	root.Instances[0].Children[0].Set("Name", rbxfile.ValueString("Part exceeding limit"))
+	data := encodeRecoverTest(t, root)
+	model := &FormatModel{LazyProperties: true}
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	types := map[int32]string{}
+	for _, chunk := range model.Chunks {
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			types[chunk.TypeID] = chunk.ClassName
+		case *ChunkProperty:
+			if chunk.PropertyName == "Name" && types[chunk.TypeID] == "Folder" {
+				// Truncate the payload so that it cannot be decoded.
+				chunk.raw = chunk.raw[:1]
+			}
+		}
+	}
+	model.Limits.MaxStringLength = len("Workspace")
+
+	root, err := RobloxCodec{Lazy: true}.Decode(model)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if n := len(model.Warnings); n != 0 {
+		t.Fatalf("expected no warnings after decode, got %d", n)
+	}
+	workspace := root.Instances[0]
+	part := workspace.Children[0]
+	folder := part.Children[0]
+	for i := 0; i < 2; i++ {
+		if v := folder.Get("Name"); v != nil {
+			t.Errorf("expected undecodable Name, got %v", v)
+		}
+		if v := part.Get("Name"); v != nil {
+			t.Errorf("expected Name exceeding limit, got %v", v)
+		}
+	}
+	if workspace.Name() != "Workspace" {
+		t.Errorf("expected Name of Workspace to be loaded")
+	}
+	if n := len(model.Warnings); n != 2 {
+		t.Fatalf("expected 2 warnings, got %d: %v", n, model.Warnings)
+	}
+	var errValue ErrValue
+	if !errors.As(model.Warnings[0], &errValue) {
+		t.Errorf("expected ErrValue, got %v", model.Warnings[0])
+	}
+	var errLimit rbxfile.ErrLimit
+	if !errors.As(model.Warnings[1], &errLimit) {
+		t.Errorf("expected ErrLimit, got %v", model.Warnings[1])
+	}
+}
+
+func TestCodecContentData(t *testing.T) {
+	const input = `<roblox version="4">
+	<Item class="MeshPart" referent="RBX0">
+		<Properties>
+			<Content name="MeshData"><binary>AQIDBA==</binary><hash>abc</hash></Content>
+			<Content name="HashOnly"><hash>def</hash></Content>
+		</Properties>
+	</Item>
+</roblox>`
+	root, err := xml.Deserialize(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("deserialize xml: %s", err)
+	}
+	if _, ok := root.Instances[0].Get("MeshData").(rbxfile.ValueContentData); !ok {
+		t.Fatalf("expected ContentData from xml")
+	}
+
+	var buf bytes.Buffer
+	if err := NewSerializer(nil, nil).Serialize(&buf, root); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	data := buf.Bytes()
+	decoded, err := NewSerializer(nil, nil).Deserialize(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	// The mapping is lossy; neither value is decoded as ContentData.
+	inst := decoded.Instances[0]
+	if v, ok := inst.Get("MeshData").(rbxfile.ValueSharedString); !ok || string(v) != "\x01\x02\x03\x04" {
+		t.Errorf("expected data as shared string, got %#v", inst.Get("MeshData"))
+	}
+	if v, ok := inst.Get("HashOnly").(rbxfile.ValueString); !ok || string(v) != "def" {
+		t.Errorf("expected hash as string, got %#v", inst.Get("HashOnly"))
+	}
+	if bytes.Contains(data, []byte("abc")) {
+		t.Error("expected hash of content with data to be discarded")
+	}
+}
+
+func TestCodecAPITypeMismatch(t *testing.T) {
+	api := &rbxapijson.Root{
+		Classes: []*rbxapijson.Class{
+			{Name: "Part", Members: []rbxapi.Member{
+				&rbxapijson.Property{Name: "Val", ValueType: rbxapijson.Type{Category: "Primitive", Name: "Float"}},
+			}},
+		},
+	}
+	root := &rbxfile.Root{}
+	for i := 0; i < 2; i++ {
+		part := rbxfile.NewInstance("Part", nil)
+		part.Set("Val", rbxfile.ValueDouble(3.5))
+		root.Instances = append(root.Instances, part)
+	}
+
+	// The existing type of the properties is preferred over the API's.
+	codec := RobloxCodec{API: api}
+	s := Serializer{Decoder: codec, Encoder: codec}
+	var buf bytes.Buffer
+	if err := s.Serialize(&buf, root); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	decoded, err := NewSerializer(nil, nil).Deserialize(&buf)
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	for _, inst := range decoded.Instances {
+		if v := inst.Get("Val"); v != rbxfile.ValueDouble(3.5) {
+			t.Errorf("unexpected value %#v", v)
+		}
+	}
+}
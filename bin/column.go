@@ -0,0 +1,991 @@
+package bin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Column is an array of values of a single Type. A typed column, such as
+// ColumnVector3, stores its values contiguously, so that decoding and encoding
+// a column does not require each value to be allocated individually. Types
+// without a typed column are stored in a ValueColumn.
+type Column interface {
+	// Type returns the type of the values in the column.
+	Type() Type
+
+	// Len returns the number of values in the column.
+	Len() int
+
+	// Value returns the value at index i. The result points into the column,
+	// so modifying it modifies the column.
+	Value(i int) Value
+
+	// SetValue sets the value at index i to a copy of v. Returns an error if
+	// v is not of the column's type.
+	SetValue(i int, v Value) error
+
+	// ArrayBytes returns the values of the column encoded as a byte array.
+	ArrayBytes() ([]byte, error)
+
+	// FromArrayBytes replaces the values of the column with values decoded
+	// from a byte array.
+	FromArrayBytes([]byte) error
+}
+
+// NewColumn returns a Column of the given Type containing n values. The
+// initial values are the same as those returned by NewValue. If the given
+// type is invalid, then nil is returned.
+func NewColumn(typ Type, n int) Column {
+	switch typ {
+	case TypeString:
+		c := make(ColumnString, n)
+		return &c
+	case TypeBool:
+		c := make(ColumnBool, n)
+		return &c
+	case TypeInt:
+		c := make(ColumnInt, n)
+		return &c
+	case TypeFloat:
+		c := make(ColumnFloat, n)
+		return &c
+	case TypeDouble:
+		c := make(ColumnDouble, n)
+		return &c
+	case TypeUDim:
+		c := make(ColumnUDim, n)
+		return &c
+	case TypeUDim2:
+		c := make(ColumnUDim2, n)
+		return &c
+	case TypeBrickColor:
+		c := make(ColumnBrickColor, n)
+		return &c
+	case TypeColor3:
+		c := make(ColumnColor3, n)
+		return &c
+	case TypeVector2:
+		c := make(ColumnVector2, n)
+		return &c
+	case TypeVector3:
+		c := make(ColumnVector3, n)
+		return &c
+	case TypeCFrame:
+		c := make(ColumnCFrame, n)
+		return &c
+	case TypeToken:
+		c := make(ColumnToken, n)
+		return &c
+	case TypeReference:
+		c := make(ColumnReference, n)
+		return &c
+	case TypeInt64:
+		c := make(ColumnInt64, n)
+		return &c
+	case TypeSharedString:
+		c := make(ColumnSharedString, n)
+		return &c
+	}
+
+	newValue, ok := valueGenerators[typ]
+	if !ok {
+		return nil
+	}
+	c := &ValueColumn{DataType: typ, Values: make([]Value, n)}
+	for i := range c.Values {
+		c.Values[i] = newValue()
+	}
+	return c
+}
+
+// Returns an error indicating that v cannot be stored in column c.
+func columnTypeError(c Column, v Value) error {
+	if v == nil {
+		return fmt.Errorf("value is nil where `%s` is expected", c.Type().String())
+	}
+	return fmt.Errorf("value is of type `%s` where `%s` is expected", v.Type().String(), c.Type().String())
+}
+
+// Encodes n values of the given byte size, where put writes value i to b, then
+// interleaves the result.
+func interleaveValues(n, size int, put func(i int, b []byte)) (b []byte, err error) {
+	b = make([]byte, n*size)
+	for i := 0; i < n; i++ {
+		put(i, b[i*size:i*size+size])
+	}
+	if err = interleave(b, size); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Returns a deinterleaved copy of b, which contains values of the given byte
+// size, along with the number of values.
+func deinterleaveValues(b []byte, size int) (bc []byte, n int, err error) {
+	if len(b) == 0 {
+		return nil, 0, nil
+	}
+	bc = make([]byte, len(b))
+	copy(bc, b)
+	if err = deinterleave(bc, size); err != nil {
+		return nil, 0, err
+	}
+	return bc, len(bc) / size, nil
+}
+
+// Encodes n values that are divided into fields, where nbytes is the length
+// of each field, and get returns the bytes of field f of value i. Each field
+// is interleaved independently.
+func interleaveColumn(n int, nbytes []int, get func(i, f int) []byte) (b []byte, err error) {
+	if n == 0 {
+		return b, nil
+	}
+
+	// Total bytes per value
+	tbytes := 0
+	// Offset of each field slice
+	ofields := make([]int, len(nbytes)+1)
+	for i, size := range nbytes {
+		tbytes += size
+		ofields[i+1] = ofields[i] + size*n
+	}
+
+	b = make([]byte, tbytes*n)
+	for f, size := range nbytes {
+		field := b[ofields[f]:ofields[f+1]]
+		for i := 0; i < n; i++ {
+			fb := get(i, f)
+			if len(fb) != size {
+				panic("length of field's bytes does not match given field length")
+			}
+			copy(field[i*size:], fb)
+		}
+		if err = interleave(field, size); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// Decodes values that are divided into fields, where nbytes is the length of
+// each field. alloc is called with the number of values, after which set is
+// called with the bytes of field f of value i. b is not modified.
+func deinterleaveColumn(b []byte, nbytes []int, alloc func(n int), set func(i, f int, b []byte) error) (err error) {
+	if len(b) == 0 {
+		alloc(0)
+		return nil
+	}
+
+	// Total bytes per value
+	tbytes := 0
+	for _, size := range nbytes {
+		tbytes += size
+	}
+	if len(b)%tbytes != 0 {
+		return fmt.Errorf("length of array (%d) is not divisible by value byte size (%d)", len(b), tbytes)
+	}
+
+	n := len(b) / tbytes
+	bc := make([]byte, len(b))
+	copy(bc, b)
+	alloc(n)
+
+	offset := 0
+	for f, size := range nbytes {
+		field := bc[offset : offset+size*n]
+		offset += size * n
+		if err = deinterleave(field, size); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err = set(i, f, field[i*size:i*size+size]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+// ValueColumn is a Column that stores each value as a Value. It is used for
+// types that do not have a typed column.
+type ValueColumn struct {
+	DataType Type
+	Values   []Value
+}
+
+func (c *ValueColumn) Type() Type {
+	return c.DataType
+}
+
+func (c *ValueColumn) Len() int {
+	return len(c.Values)
+}
+
+func (c *ValueColumn) Value(i int) Value {
+	return c.Values[i]
+}
+
+func (c *ValueColumn) SetValue(i int, v Value) error {
+	if v == nil || v.Type() != c.DataType {
+		return columnTypeError(c, v)
+	}
+	c.Values[i] = v
+	return nil
+}
+
+func (c *ValueColumn) ArrayBytes() ([]byte, error) {
+	v := NewValue(c.DataType)
+	if v == nil {
+		return nil, fmt.Errorf("type identifier 0x%X is not a valid Type", byte(c.DataType))
+	}
+	return v.ArrayBytes(c.Values)
+}
+
+func (c *ValueColumn) FromArrayBytes(b []byte) (err error) {
+	v := NewValue(c.DataType)
+	if v == nil {
+		return fmt.Errorf("type identifier 0x%X is not a valid Type", byte(c.DataType))
+	}
+	values, err := v.FromArrayBytes(b)
+	if err != nil {
+		return err
+	}
+	c.Values = values
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnString []ValueString
+
+func (*ColumnString) Type() Type {
+	return TypeString
+}
+
+func (c *ColumnString) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnString) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnString) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueString)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnString) ArrayBytes() (b []byte, err error) {
+	size := 0
+	for _, v := range *c {
+		size += 4 + len(v)
+	}
+	b = make([]byte, 0, size)
+	for _, v := range *c {
+		b = append(b, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b, nil
+}
+
+func (c *ColumnString) FromArrayBytes(b []byte) error {
+	// Copy the array once, so that each string can refer to a portion of it.
+	bc := make([]byte, len(b))
+	copy(bc, b)
+	*c = (*c)[:0]
+	for len(bc) > 0 {
+		if len(bc) < 4 {
+			return errors.New("expected 4 more bytes in array")
+		}
+		size := int(binary.LittleEndian.Uint32(bc))
+		if len(bc[4:]) < size {
+			return fmt.Errorf("expected %d more bytes in array", size)
+		}
+		*c = append(*c, ValueString(bc[4:4+size:4+size]))
+		bc = bc[4+size:]
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnBool []ValueBool
+
+func (*ColumnBool) Type() Type {
+	return TypeBool
+}
+
+func (c *ColumnBool) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnBool) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnBool) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueBool)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnBool) ArrayBytes() (b []byte, err error) {
+	b = make([]byte, len(*c))
+	for i, v := range *c {
+		if v {
+			b[i] = 1
+		}
+	}
+	return b, nil
+}
+
+func (c *ColumnBool) FromArrayBytes(b []byte) error {
+	*c = make(ColumnBool, len(b))
+	for i, v := range b {
+		(*c)[i] = v != 0
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnInt []ValueInt
+
+func (*ColumnInt) Type() Type {
+	return TypeInt
+}
+
+func (c *ColumnInt) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnInt) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnInt) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueInt)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnInt) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		binary.BigEndian.PutUint32(b, encodeZigzag32(int32((*c)[i])))
+	})
+}
+
+func (c *ColumnInt) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnInt, n)
+	for i := range *c {
+		(*c)[i] = ValueInt(decodeZigzag32(binary.BigEndian.Uint32(bc[i*4:])))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnFloat []ValueFloat
+
+func (*ColumnFloat) Type() Type {
+	return TypeFloat
+}
+
+func (c *ColumnFloat) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnFloat) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnFloat) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueFloat)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnFloat) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		binary.BigEndian.PutUint32(b, encodeRobloxFloat(float32((*c)[i])))
+	})
+}
+
+func (c *ColumnFloat) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnFloat, n)
+	for i := range *c {
+		(*c)[i] = ValueFloat(decodeRobloxFloat(binary.BigEndian.Uint32(bc[i*4:])))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnDouble []ValueDouble
+
+func (*ColumnDouble) Type() Type {
+	return TypeDouble
+}
+
+func (c *ColumnDouble) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnDouble) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnDouble) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueDouble)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnDouble) ArrayBytes() (b []byte, err error) {
+	b = make([]byte, len(*c)*8)
+	for i, v := range *c {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(float64(v)))
+	}
+	return b, nil
+}
+
+func (c *ColumnDouble) FromArrayBytes(b []byte) error {
+	*c = make(ColumnDouble, len(b)/8)
+	for i := range *c {
+		(*c)[i] = ValueDouble(math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:])))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnUDim []ValueUDim
+
+func (*ColumnUDim) Type() Type {
+	return TypeUDim
+}
+
+func (c *ColumnUDim) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnUDim) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnUDim) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueUDim)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnUDim) ArrayBytes() ([]byte, error) {
+	return interleaveColumn(len(*c), ValueUDim{}.fieldLen(), func(i, f int) []byte {
+		return (*c)[i].fieldGet(f)
+	})
+}
+
+func (c *ColumnUDim) FromArrayBytes(b []byte) error {
+	return deinterleaveColumn(b, ValueUDim{}.fieldLen(),
+		func(n int) { *c = make(ColumnUDim, n) },
+		func(i, f int, b []byte) error { return (*c)[i].fieldSet(f, b) },
+	)
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnUDim2 []ValueUDim2
+
+func (*ColumnUDim2) Type() Type {
+	return TypeUDim2
+}
+
+func (c *ColumnUDim2) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnUDim2) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnUDim2) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueUDim2)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnUDim2) ArrayBytes() ([]byte, error) {
+	return interleaveColumn(len(*c), ValueUDim2{}.fieldLen(), func(i, f int) []byte {
+		return (*c)[i].fieldGet(f)
+	})
+}
+
+func (c *ColumnUDim2) FromArrayBytes(b []byte) error {
+	return deinterleaveColumn(b, ValueUDim2{}.fieldLen(),
+		func(n int) { *c = make(ColumnUDim2, n) },
+		func(i, f int, b []byte) error { return (*c)[i].fieldSet(f, b) },
+	)
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnBrickColor []ValueBrickColor
+
+func (*ColumnBrickColor) Type() Type {
+	return TypeBrickColor
+}
+
+func (c *ColumnBrickColor) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnBrickColor) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnBrickColor) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueBrickColor)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnBrickColor) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		binary.BigEndian.PutUint32(b, uint32((*c)[i]))
+	})
+}
+
+func (c *ColumnBrickColor) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnBrickColor, n)
+	for i := range *c {
+		(*c)[i] = ValueBrickColor(binary.BigEndian.Uint32(bc[i*4:]))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnColor3 []ValueColor3
+
+func (*ColumnColor3) Type() Type {
+	return TypeColor3
+}
+
+func (c *ColumnColor3) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnColor3) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnColor3) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueColor3)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnColor3) ArrayBytes() ([]byte, error) {
+	return interleaveColumn(len(*c), ValueColor3{}.fieldLen(), func(i, f int) []byte {
+		return (*c)[i].fieldGet(f)
+	})
+}
+
+func (c *ColumnColor3) FromArrayBytes(b []byte) error {
+	return deinterleaveColumn(b, ValueColor3{}.fieldLen(),
+		func(n int) { *c = make(ColumnColor3, n) },
+		func(i, f int, b []byte) error { return (*c)[i].fieldSet(f, b) },
+	)
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnVector2 []ValueVector2
+
+func (*ColumnVector2) Type() Type {
+	return TypeVector2
+}
+
+func (c *ColumnVector2) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnVector2) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnVector2) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueVector2)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnVector2) ArrayBytes() ([]byte, error) {
+	return interleaveColumn(len(*c), ValueVector2{}.fieldLen(), func(i, f int) []byte {
+		return (*c)[i].fieldGet(f)
+	})
+}
+
+func (c *ColumnVector2) FromArrayBytes(b []byte) error {
+	return deinterleaveColumn(b, ValueVector2{}.fieldLen(),
+		func(n int) { *c = make(ColumnVector2, n) },
+		func(i, f int, b []byte) error { return (*c)[i].fieldSet(f, b) },
+	)
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnVector3 []ValueVector3
+
+func (*ColumnVector3) Type() Type {
+	return TypeVector3
+}
+
+func (c *ColumnVector3) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnVector3) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnVector3) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueVector3)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnVector3) ArrayBytes() ([]byte, error) {
+	return interleaveColumn(len(*c), ValueVector3{}.fieldLen(), func(i, f int) []byte {
+		return (*c)[i].fieldGet(f)
+	})
+}
+
+func (c *ColumnVector3) FromArrayBytes(b []byte) error {
+	return deinterleaveColumn(b, ValueVector3{}.fieldLen(),
+		func(n int) { *c = make(ColumnVector3, n) },
+		func(i, f int, b []byte) error { return (*c)[i].fieldSet(f, b) },
+	)
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnCFrame []ValueCFrame
+
+func (*ColumnCFrame) Type() Type {
+	return TypeCFrame
+}
+
+func (c *ColumnCFrame) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnCFrame) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnCFrame) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueCFrame)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnCFrame) ArrayBytes() (b []byte, err error) {
+	// Build matrix part.
+	positions := make(ColumnVector3, len(*c))
+	for i, cf := range *c {
+		b = append(b, cf.Special)
+		if cf.Special == 0 {
+			for _, f := range cf.Rotation {
+				b = append(b, 0, 0, 0, 0)
+				binary.LittleEndian.PutUint32(b[len(b)-4:], math.Float32bits(f))
+			}
+		}
+		positions[i] = cf.Position
+	}
+
+	// Build position part.
+	pb, err := positions.ArrayBytes()
+	if err != nil {
+		return nil, err
+	}
+	return append(b, pb...), nil
+}
+
+func (c *ColumnCFrame) FromArrayBytes(b []byte) error {
+	*c = (*c)[:0]
+
+	// Read matrix data while the number of remaining bytes is greater than
+	// the expected size of the position data. See ValueCFrame.FromArrayBytes.
+	i := 0
+	for n := 0; len(b)-i > n; n += 12 {
+		var cf ValueCFrame
+		cf.Special = b[i]
+		i++
+		if cf.Special == 0 {
+			q := len(cf.Rotation) * 4
+			r := b[i:]
+			if len(r) < q {
+				return fmt.Errorf("expected %d more bytes in array", q)
+			}
+			for j := range cf.Rotation {
+				cf.Rotation[j] = math.Float32frombits(binary.LittleEndian.Uint32(r[j*4 : j*4+4]))
+			}
+			i += q
+		}
+		*c = append(*c, cf)
+	}
+
+	var positions ColumnVector3
+	if err := positions.FromArrayBytes(b[i:]); err != nil {
+		return err
+	}
+	if len(positions) != len(*c) {
+		return errors.New("number of positions does not match number of matrices")
+	}
+	for j, p := range positions {
+		(*c)[j].Position = p
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnToken []ValueToken
+
+func (*ColumnToken) Type() Type {
+	return TypeToken
+}
+
+func (c *ColumnToken) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnToken) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnToken) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueToken)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnToken) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		binary.BigEndian.PutUint32(b, uint32((*c)[i]))
+	})
+}
+
+func (c *ColumnToken) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnToken, n)
+	for i := range *c {
+		(*c)[i] = ValueToken(binary.BigEndian.Uint32(bc[i*4:]))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnReference []ValueReference
+
+func (*ColumnReference) Type() Type {
+	return TypeReference
+}
+
+func (c *ColumnReference) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnReference) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnReference) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueReference)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnReference) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		ref := (*c)[i]
+		if i > 0 {
+			// Convert absolute ref to relative ref.
+			ref -= (*c)[i-1]
+		}
+		binary.BigEndian.PutUint32(b, encodeZigzag32(int32(ref)))
+	})
+}
+
+func (c *ColumnReference) FromArrayBytes(b []byte) error {
+	if len(b)%4 != 0 {
+		return fmt.Errorf("array must be divisible by %d", 4)
+	}
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnReference, n)
+	for i := range *c {
+		ref := ValueReference(decodeZigzag32(binary.BigEndian.Uint32(bc[i*4:])))
+		if i > 0 {
+			// Convert relative ref to absolute ref.
+			ref += (*c)[i-1]
+		}
+		(*c)[i] = ref
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnInt64 []ValueInt64
+
+func (*ColumnInt64) Type() Type {
+	return TypeInt64
+}
+
+func (c *ColumnInt64) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnInt64) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnInt64) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueInt64)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnInt64) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 8, func(i int, b []byte) {
+		binary.BigEndian.PutUint64(b, encodeZigzag64(int64((*c)[i])))
+	})
+}
+
+func (c *ColumnInt64) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 8)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnInt64, n)
+	for i := range *c {
+		(*c)[i] = ValueInt64(decodeZigzag64(binary.BigEndian.Uint64(bc[i*8:])))
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+
+type ColumnSharedString []ValueSharedString
+
+func (*ColumnSharedString) Type() Type {
+	return TypeSharedString
+}
+
+func (c *ColumnSharedString) Len() int {
+	return len(*c)
+}
+
+func (c *ColumnSharedString) Value(i int) Value {
+	return &(*c)[i]
+}
+
+func (c *ColumnSharedString) SetValue(i int, v Value) error {
+	value, ok := v.(*ValueSharedString)
+	if !ok {
+		return columnTypeError(c, v)
+	}
+	(*c)[i] = *value
+	return nil
+}
+
+func (c *ColumnSharedString) ArrayBytes() ([]byte, error) {
+	return interleaveValues(len(*c), 4, func(i int, b []byte) {
+		binary.BigEndian.PutUint32(b, uint32((*c)[i]))
+	})
+}
+
+func (c *ColumnSharedString) FromArrayBytes(b []byte) error {
+	bc, n, err := deinterleaveValues(b, 4)
+	if err != nil {
+		return err
+	}
+	*c = make(ColumnSharedString, n)
+	for i := range *c {
+		(*c)[i] = ValueSharedString(binary.BigEndian.Uint32(bc[i*4:]))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package bin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+func columnTestRoot() *rbxfile.Root {
+	root := recoverTestRoot()
+	part := root.Instances[0].Children[0]
+	for i, name := range []string{"A", "B", "C"} {
+		inst := rbxfile.NewInstance("Part", part)
+		inst.Set("Name", rbxfile.ValueString(name))
+		inst.Set("Size", rbxfile.ValueVector3{X: float32(i), Y: -1.5, Z: 2})
+		inst.Set("Color", rbxfile.ValueColor3{R: 0.5, G: float32(i), B: 1})
+		inst.Set("Transparency", rbxfile.ValueFloat(float32(i)/4))
+		inst.Set("Count", rbxfile.ValueInt(-i))
+		inst.Set("Big", rbxfile.ValueInt64(-1<<40*int64(i)))
+		inst.Set("Mass", rbxfile.ValueDouble(float64(i)*1.25))
+		inst.Set("Target", rbxfile.ValueReference{Instance: part})
+		cf := rbxfile.ValueCFrame{Position: rbxfile.ValueVector3{X: 1, Y: 2, Z: float32(i)}}
+		cf.Rotation = [9]float32{1, 0, 0, 0, 1, 0, 0, 0, 1}
+		if i == 1 {
+			cf.Rotation[0] = 0.5
+		}
+		inst.Set("CFrame", cf)
+		inst.Set("Offset", rbxfile.ValueUDim2{
+			X: rbxfile.ValueUDim{Scale: 0.5, Offset: int32(i)},
+			Y: rbxfile.ValueUDim{Scale: 1, Offset: -3},
+		})
+		inst.Set("Range", rbxfile.ValueNumberRange{Min: 1, Max: float32(i)})
+	}
+	return root
+}
+
+func TestColumns(t *testing.T) {
+	inputs := map[string][]byte{
+		"columns": encodeRecoverTest(t, columnTestRoot()),
+	}
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.rbxm"))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[file] = b
+	}
+
+	for name, data := range inputs {
+		model := &FormatModel{LazyProperties: true}
+		if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: read: %s", name, err)
+		}
+		for _, chunk := range model.Chunks {
+			chunk, ok := chunk.(*ChunkProperty)
+			if !ok {
+				continue
+			}
+			raw := append([]byte(nil), chunk.raw...)
+
+			// Typed columns must encode to the same bytes as the Value API.
+			column := NewColumn(chunk.DataType, 0)
+			if err := column.FromArrayBytes(raw); err != nil {
+				t.Errorf("%s: %s: column: %s", name, chunk.PropertyName, err)
+				continue
+			}
+			values, err := NewValue(chunk.DataType).FromArrayBytes(append([]byte(nil), raw...))
+			if err != nil {
+				t.Errorf("%s: %s: values: %s", name, chunk.PropertyName, err)
+				continue
+			}
+			if column.Len() != len(values) {
+				t.Errorf("%s: %s: expected %d values, got %d", name, chunk.PropertyName, len(values), column.Len())
+				continue
+			}
+			for i, v := range values {
+				if !bytes.Equal(column.Value(i).Bytes(), v.Bytes()) {
+					t.Errorf("%s: %s: value #%d differs", name, chunk.PropertyName, i)
+				}
+			}
+			b, err := column.ArrayBytes()
+			if err != nil {
+				t.Errorf("%s: %s: encode: %s", name, chunk.PropertyName, err)
+			} else if !bytes.Equal(b, raw) {
+				t.Errorf("%s: %s: expected column to encode to original bytes", name, chunk.PropertyName)
+			}
+
+			// Compatibility with Properties.
+			compat := &ChunkProperty{DataType: chunk.DataType, Properties: values}
+			var buf bytes.Buffer
+			if _, err := compat.WriteTo(&buf); err != nil {
+				t.Errorf("%s: %s: write: %s", name, chunk.PropertyName, err)
+			} else if !bytes.HasSuffix(buf.Bytes(), raw) {
+				t.Errorf("%s: %s: expected Properties to encode to original bytes", name, chunk.PropertyName)
+			}
+		}
+	}
+
+	column := NewColumn(TypeVector3, 1)
+	if err := column.SetValue(0, new(ValueFloat)); err == nil {
+		t.Error("expected error for mismatched type")
+	}
+	v := &ValueVector3{X: 1, Y: 2, Z: 3}
+	if err := column.SetValue(0, v); err != nil {
+		t.Fatal(err)
+	}
+	if got := column.Value(0).(*ValueVector3); *got != *v {
+		t.Errorf("expected %v, got %v", *v, *got)
+	}
+}
@@ -38,7 +38,9 @@ package bin
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxfile"
 	"github.com/robloxapi/rbxfile/xml"
@@ -66,6 +68,10 @@ type Serializer struct {
 	// the XML format. If so, then it will be decoded using an xml.Serializer
 	// with the given decoder.
 	DecoderXML xml.Decoder
+
+	// Limits bounds the resources used while deserializing. It is passed to
+	// the FormatModel, or to the serializer used to decode the XML format.
+	Limits rbxfile.Limits
 }
 
 // NewSerializer returns a new Serializer with a specified decoder and
@@ -98,6 +104,9 @@ func (s Serializer) Deserialize(r io.Reader) (root *rbxfile.Root, err error) {
 	if s.Decoder == nil {
 		return nil, errors.New("a decoder has not been not specified")
 	}
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
 
 	if s.DecoderXML != nil {
 		var buf *bufio.Reader
@@ -116,25 +125,204 @@ func (s Serializer) Deserialize(r io.Reader) (root *rbxfile.Root, err error) {
 		}
 
 		if !bytes.Equal(sig[len(RobloxSig):], []byte(BinaryMarker)) {
-			return xml.NewSerializer(s.DecoderXML, nil).Deserialize(buf)
+			return xml.Serializer{Decoder: s.DecoderXML, Limits: s.Limits}.Deserialize(buf)
 		}
 		r = buf
 	}
 
-	model := new(FormatModel)
+	model := &FormatModel{Limits: s.Limits}
 
 	if _, err = model.ReadFrom(r); err != nil {
-		return nil, errors.New("error parsing format: " + err.Error())
+		return nil, fmt.Errorf("error parsing format: %w", err)
 	}
 
 	root, err = s.Decoder.Decode(model)
 	if err != nil {
-		return nil, errors.New("error decoding data: " + err.Error())
+		return nil, fmt.Errorf("error decoding data: %w", err)
 	}
 
 	return root, nil
 }
 
+// DeserializeContext is like Deserialize, but stops once ctx is done,
+// returning ctx.Err(). If progress is not nil, it receives the progress of
+// reading and decoding. Streams in the XML format are passed to
+// xml.Serializer.DeserializeContext.
+func (s Serializer) DeserializeContext(ctx context.Context, r io.Reader, progress rbxfile.ProgressFunc) (root *rbxfile.Root, err error) {
+	if s.Decoder == nil {
+		return nil, errors.New("a decoder has not been not specified")
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.DecoderXML != nil {
+		var buf *bufio.Reader
+		if br, ok := r.(*bufio.Reader); ok {
+			buf = br
+		} else {
+			buf = bufio.NewReader(r)
+		}
+
+		sig, err := buf.Peek(len(RobloxSig) + len(BinaryMarker))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(sig[:len(RobloxSig)], []byte(RobloxSig)) {
+			return nil, ErrInvalidSig
+		}
+
+		if !bytes.Equal(sig[len(RobloxSig):], []byte(BinaryMarker)) {
+			return xml.Serializer{Decoder: s.DecoderXML, Limits: s.Limits}.DeserializeContext(ctx, buf, progress)
+		}
+		r = buf
+	}
+
+	model := &FormatModel{Limits: s.Limits, Context: ctx, Progress: progress}
+
+	if _, err = model.ReadFrom(r); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error parsing format: %w", err)
+	}
+
+	root, err = s.Decoder.Decode(model)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	return root, nil
+}
+
+// DeserializeRecover is like Deserialize, but attempts to salvage as much data
+// as possible from a corrupted stream by setting FormatModel.Recover. In
+// addition to the decoded Root, a report is returned that describes what was
+// lost. An error is returned only if the stream could not be read at all.
+//
+// Streams in the XML format are decoded normally, with an empty report.
+func (s Serializer) DeserializeRecover(r io.Reader) (root *rbxfile.Root, report *RecoveryReport, err error) {
+	if s.Decoder == nil {
+		return nil, nil, errors.New("a decoder has not been not specified")
+	}
+
+	if s.DecoderXML != nil {
+		var buf *bufio.Reader
+		if br, ok := r.(*bufio.Reader); ok {
+			buf = br
+		} else {
+			buf = bufio.NewReader(r)
+		}
+
+		sig, err := buf.Peek(len(RobloxSig) + len(BinaryMarker))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !bytes.Equal(sig[:len(RobloxSig)], []byte(RobloxSig)) {
+			return nil, nil, ErrInvalidSig
+		}
+
+		if !bytes.Equal(sig[len(RobloxSig):], []byte(BinaryMarker)) {
+			root, err = xml.Serializer{Decoder: s.DecoderXML, Limits: s.Limits}.Deserialize(buf)
+			if err != nil {
+				return nil, nil, err
+			}
+			return root, new(RecoveryReport), nil
+		}
+		r = buf
+	}
+
+	model := &FormatModel{Recover: true, Limits: s.Limits}
+
+	if _, err = model.ReadFrom(r); err != nil {
+		return nil, nil, fmt.Errorf("error parsing format: %w", err)
+	}
+
+	root, err = s.Decoder.Decode(model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	return root, model.Report(), nil
+}
+
+// DeserializeLayout is like Deserialize, but also returns the Layout of the
+// decoded Root, which can be passed to SerializeLayout. The FormatModel is
+// read with PreserveLayout set. The decoder must implement LayoutDecoder.
+//
+// The XML format is not supported.
+func (s Serializer) DeserializeLayout(r io.Reader) (root *rbxfile.Root, layout *Layout, err error) {
+	decoder, ok := s.Decoder.(LayoutDecoder)
+	if !ok {
+		return nil, nil, errors.New("decoder does not support layouts")
+	}
+
+	model := &FormatModel{PreserveLayout: true, Limits: s.Limits}
+
+	if _, err = model.ReadFrom(r); err != nil {
+		return nil, nil, fmt.Errorf("error parsing format: %w", err)
+	}
+
+	root, layout, err = decoder.DecodeLayout(model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	return root, layout, nil
+}
+
+// SerializeLayout is like Serialize, but encodes root according to layout, as
+// returned by DeserializeLayout. If root has not been modified, then the
+// original stream is reproduced exactly. The encoder must implement
+// LayoutEncoder.
+func (s Serializer) SerializeLayout(w io.Writer, root *rbxfile.Root, layout *Layout) (err error) {
+	encoder, ok := s.Encoder.(LayoutEncoder)
+	if !ok {
+		return errors.New("encoder does not support layouts")
+	}
+
+	model, err := encoder.EncodeLayout(root, layout)
+	if err != nil {
+		return fmt.Errorf("error encoding data: %w", err)
+	}
+
+	if _, err = model.WriteTo(w); err != nil {
+		return fmt.Errorf("error encoding format: %w", err)
+	}
+
+	return nil
+}
+
+// SerializeContext is like Serialize, but stops once ctx is done, returning
+// ctx.Err(). The context is checked before encoding, and between chunks while
+// writing. If progress is not nil, it receives the progress of writing.
+func (s Serializer) SerializeContext(ctx context.Context, w io.Writer, root *rbxfile.Root, progress rbxfile.ProgressFunc) (err error) {
+	if s.Encoder == nil {
+		return errors.New("an encoder has not been not specified")
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	model, err := s.Encoder.Encode(root)
+	if err != nil {
+		return fmt.Errorf("error encoding data: %w", err)
+	}
+
+	model.Context, model.Progress = ctx, progress
+	if _, err = model.WriteTo(w); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("error encoding format: %w", err)
+	}
+
+	return nil
+}
+
 // Serialize encodes data from a Root structure to w using the specified
 // encoder.
 func (s Serializer) Serialize(w io.Writer, root *rbxfile.Root) (err error) {
@@ -144,11 +332,11 @@ func (s Serializer) Serialize(w io.Writer, root *rbxfile.Root) (err error) {
 
 	model, err := s.Encoder.Encode(root)
 	if err != nil {
-		return errors.New("error encoding data: " + err.Error())
+		return fmt.Errorf("error encoding data: %w", err)
 	}
 
 	if _, err = model.WriteTo(w); err != nil {
-		return errors.New("error encoding format: " + err.Error())
+		return fmt.Errorf("error encoding format: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,71 @@
+//go:build go1.18
+// +build go1.18
+
+package bin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/internal/fuzzseed"
+)
+
+// Limits used while fuzzing, so that crafted lengths do not exhaust memory.
+var fuzzLimits = rbxfile.Limits{
+	MaxChunkSize:  1 << 20,
+	MaxTotalBytes: 1 << 22,
+	MaxInstances:  1 << 16,
+	MaxProperties: 1 << 18,
+}
+
+// addSeeds adds the seed corpus for inputs with the given extensions, along
+// with a minimal file.
+func addSeeds(f *testing.F, exts ...string) {
+	fuzzseed.Add(f, exts...)
+	f.Add([]byte(RobloxSig + BinaryMarker + BinaryHeader))
+}
+
+func FuzzFormatModelReadFrom(f *testing.F) {
+	addSeeds(f, ".rbxl", ".rbxm")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		model := &FormatModel{Limits: fuzzLimits}
+		model.ReadFrom(bytes.NewReader(b))
+
+		model = &FormatModel{Recover: true, Limits: fuzzLimits}
+		model.ReadFrom(bytes.NewReader(b))
+		model.Report()
+	})
+}
+
+func FuzzRobloxCodecDecode(f *testing.F) {
+	addSeeds(f, ".rbxl", ".rbxm")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, recover := range []bool{false, true} {
+			model := &FormatModel{Recover: recover, Limits: fuzzLimits}
+			if _, err := model.ReadFrom(bytes.NewReader(b)); err != nil {
+				continue
+			}
+			RobloxCodec{Mode: ModeModel}.Decode(model)
+			model.Report()
+		}
+
+		// Lazily decoded properties are decoded only when loaded.
+		model := &FormatModel{LazyProperties: true, Limits: fuzzLimits}
+		if _, err := model.ReadFrom(bytes.NewReader(b)); err != nil {
+			return
+		}
+		root, err := RobloxCodec{Mode: ModeModel, Lazy: true}.Decode(model)
+		if err != nil {
+			return
+		}
+		var load func(instances []*rbxfile.Instance)
+		load = func(instances []*rbxfile.Instance) {
+			for _, inst := range instances {
+				inst.LoadProperties()
+				load(inst.Children)
+			}
+		}
+		load(root.Instances)
+	})
+}
@@ -0,0 +1,353 @@
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/robloxapi/rbxfile"
+	"io"
+)
+
+// Size of the file header, which precedes the first chunk.
+const fileHeaderSize = len(RobloxSig+BinaryMarker+BinaryHeader) + 2 + 4 + 4 + 8
+
+// Index records the location of each chunk in a binary file, so that chunks
+// can be decoded individually, without reading the entire file. Creating an
+// Index reads only the header of each chunk, along with the few bytes of
+// ChunkInstance and ChunkProperty payloads that identify them.
+type Index struct {
+	// Version, TypeCount and InstanceCount are read from the file header.
+	Version       uint16
+	TypeCount     uint32
+	InstanceCount uint32
+
+	// Entries describes each chunk in the file, in order.
+	Entries []IndexEntry
+
+	// Limits bounds the resources used by NewIndex, ReadChunk and Model. The
+	// LimitTotalBytes limit applies separately to each call to ReadChunk or
+	// Model, counting the file header and the decompressed bytes of the
+	// chunks read by that call.
+	Limits rbxfile.Limits
+
+	r io.ReaderAt
+}
+
+// IndexEntry describes the location of a chunk within a file.
+type IndexEntry struct {
+	// Offset is the location of the chunk header, in bytes from the start of
+	// the file.
+	Offset int64
+
+	// Sig is the signature of the chunk.
+	Sig [4]byte
+
+	// Compressed indicates whether the chunk is compressed.
+	Compressed bool
+
+	// Size is the length of the chunk as it appears in the file, including
+	// the header.
+	Size int64
+
+	// PayloadSize is the length of the decompressed payload.
+	PayloadSize int64
+
+	// TypeID is the instance group of a ChunkInstance or ChunkProperty, and
+	// is -1 for other chunks.
+	TypeID int32
+
+	// ClassName is the class of the instance group. For a ChunkProperty, it
+	// is resolved from the ChunkInstance with the same TypeID, and is empty
+	// if no such chunk exists.
+	ClassName string
+
+	// PropertyName is the name of the property of a ChunkProperty.
+	PropertyName string
+}
+
+// NewIndex creates an Index by reading the chunk headers from r. Chunks are
+// indexed up to and including the end chunk, or until the end of the file.
+// The lengths of each chunk are checked against limits before any part of
+// the chunk is read. limits is also used as the Limits of the Index.
+func NewIndex(r io.ReaderAt, limits rbxfile.Limits) (index *Index, err error) {
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
+
+	header := make([]byte, fileHeaderSize)
+	if _, err = r.ReadAt(header, 0); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	n := len(RobloxSig + BinaryMarker)
+	if !bytes.Equal(header[:n], []byte(RobloxSig+BinaryMarker)) {
+		return nil, ErrInvalidSig
+	}
+	if !bytes.Equal(header[n:n+len(BinaryHeader)], []byte(BinaryHeader)) {
+		return nil, ErrCorruptHeader
+	}
+	n += len(BinaryHeader)
+
+	index = &Index{Limits: limits, r: r}
+	index.Version = binary.LittleEndian.Uint16(header[n:])
+	if index.Version != 0 {
+		return nil, ErrUnrecognizedVersion(index.Version)
+	}
+	index.TypeCount = binary.LittleEndian.Uint32(header[n+2:])
+	index.InstanceCount = binary.LittleEndian.Uint32(header[n+6:])
+
+	classes := map[int32]string{}
+	offset := int64(fileHeaderSize)
+	for {
+		entry, err := index.readEntry(offset)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("chunk at %d: %w", offset, err)
+		}
+		if entry.Sig == (ChunkInstance{}).Signature() {
+			classes[entry.TypeID] = entry.ClassName
+		}
+		index.Entries = append(index.Entries, entry)
+		if entry.Sig == (ChunkEnd{}).Signature() {
+			break
+		}
+		offset += entry.Size
+	}
+
+	for i, entry := range index.Entries {
+		if entry.Sig == (ChunkProperty{}).Signature() {
+			index.Entries[i].ClassName = classes[entry.TypeID]
+		}
+	}
+
+	return index, nil
+}
+
+// readEntry reads the header of the chunk at offset. Returns io.EOF if no
+// bytes remain.
+func (x *Index) readEntry(offset int64) (entry IndexEntry, err error) {
+	header := make([]byte, rawChunkHeaderSize)
+	if n, err := x.r.ReadAt(header, offset); err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return entry, err
+	}
+
+	entry.Offset = offset
+	entry.TypeID = -1
+	copy(entry.Sig[:], header[0:4])
+	compressedLength := binary.LittleEndian.Uint32(header[4:8])
+	decompressedLength := binary.LittleEndian.Uint32(header[8:12])
+	if err = x.Limits.Check(rbxfile.LimitChunkSize, int64(decompressedLength)); err != nil {
+		return entry, err
+	}
+	if err = x.Limits.Check(rbxfile.LimitChunkSize, int64(compressedLength)); err != nil {
+		return entry, err
+	}
+	entry.Compressed = compressedLength != 0
+	entry.PayloadSize = int64(decompressedLength)
+	entry.Size = rawChunkHeaderSize + int64(decompressedLength)
+	if entry.Compressed {
+		entry.Size = rawChunkHeaderSize + int64(compressedLength)
+	}
+
+	var name *string
+	switch entry.Sig {
+	case ChunkInstance{}.Signature():
+		name = &entry.ClassName
+	case ChunkProperty{}.Signature():
+		name = &entry.PropertyName
+	default:
+		return entry, nil
+	}
+
+	// Read the type ID and name at the start of the payload.
+	prefix, err := x.readPrefix(entry, 8)
+	if err != nil {
+		return entry, err
+	}
+	entry.TypeID = int32(binary.LittleEndian.Uint32(prefix[0:4]))
+	length := int64(binary.LittleEndian.Uint32(prefix[4:8]))
+	if length > entry.PayloadSize-8 {
+		return entry, io.ErrUnexpectedEOF
+	}
+	if prefix, err = x.readPrefix(entry, 8+int(length)); err != nil {
+		return entry, err
+	}
+	*name = string(prefix[8 : 8+length])
+	return entry, nil
+}
+
+// readPrefix returns at least the first n bytes of the decompressed payload
+// of a chunk, reading as little of the chunk as possible.
+func (x *Index) readPrefix(entry IndexEntry, n int) (prefix []byte, err error) {
+	if int64(n) > entry.PayloadSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body := entry.Offset + rawChunkHeaderSize
+	if !entry.Compressed {
+		prefix = make([]byte, n)
+		if _, err = x.r.ReadAt(prefix, body); err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return prefix, err
+	}
+
+	// Read increasingly large portions of the compressed data until enough
+	// has been decompressed.
+	size := entry.Size - rawChunkHeaderSize
+	for length := int64(n + 64); ; length *= 2 {
+		if length > size {
+			length = size
+		}
+		data := make([]byte, length)
+		if _, err = x.r.ReadAt(data, body); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		prefix, err = lz4Prefix(data, n)
+		if err != errShortBlock {
+			return prefix, err
+		}
+		if length == size {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+var errShortBlock = errors.New("lz4: block too short")
+
+// lz4Prefix decompresses at least the first n bytes of the LZ4 block in src.
+// Returns errShortBlock if src ends before n bytes were decompressed.
+func lz4Prefix(src []byte, n int) (dst []byte, err error) {
+	dst = make([]byte, 0, n)
+	i := 0
+	length := func(l int) (int, bool) {
+		if l != 15 {
+			return l, true
+		}
+		for i < len(src) {
+			b := src[i]
+			i++
+			l += int(b)
+			if b != 255 {
+				return l, true
+			}
+		}
+		return l, false
+	}
+	for len(dst) < n {
+		if i >= len(src) {
+			return nil, errShortBlock
+		}
+		token := src[i]
+		i++
+
+		literals, ok := length(int(token >> 4))
+		if !ok {
+			return nil, errShortBlock
+		}
+		if len(src)-i < literals {
+			dst = append(dst, src[i:]...)
+			if len(dst) >= n {
+				return dst, nil
+			}
+			return nil, errShortBlock
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if len(dst) >= n {
+			break
+		}
+
+		if len(src)-i < 2 {
+			return nil, errShortBlock
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errors.New("lz4: invalid offset")
+		}
+		match, ok := length(int(token & 15))
+		if !ok {
+			return nil, errShortBlock
+		}
+		// Copy byte-by-byte, since the match may overlap itself.
+		for j, start := 0, len(dst)-offset; j < match+4; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+	return dst, nil
+}
+
+// ReadChunk reads and decodes the chunk of the entry at index i.
+func (x *Index) ReadChunk(i int) (chunk Chunk, err error) {
+	total := int64(fileHeaderSize)
+	return x.readChunk(i, &total)
+}
+
+// readChunk reads and decodes the chunk of the entry at index i. total is the
+// number of bytes read so far by the current operation, and is increased by
+// the size of the chunk.
+func (x *Index) readChunk(i int, total *int64) (chunk Chunk, err error) {
+	if i < 0 || i >= len(x.Entries) {
+		return nil, errors.New("index out of range")
+	}
+	entry := x.Entries[i]
+
+	fr := &formatReader{r: io.NewSectionReader(x.r, entry.Offset, entry.Size)}
+	raw := new(rawChunk)
+	if raw.ReadFrom(fr, x.Limits, *total) {
+		_, err = fr.end()
+		return nil, ErrChunk{Sig: entry.Sig, Err: err}
+	}
+	*total += rawChunkHeaderSize + int64(len(raw.payload))
+
+	if chunk, err = decodeRawChunk(x.Version, raw, false); err != nil {
+		return nil, ErrChunk{Sig: entry.Sig, Err: err}
+	}
+	return chunk, nil
+}
+
+// Model returns a FormatModel containing the chunks selected by filter. The
+// ChunkInstance, ChunkParent and ChunkEnd chunks, which describe the
+// structure of the tree, are always included. Other chunks are read only if
+// filter returns true for their entry. If filter is nil, then all chunks are
+// read.
+//
+// The resulting model can be decoded as usual, yielding instances that have
+// only the selected properties.
+func (x *Index) Model(filter func(entry IndexEntry) bool) (model *FormatModel, err error) {
+	model = &FormatModel{
+		Version:       x.Version,
+		TypeCount:     x.TypeCount,
+		InstanceCount: x.InstanceCount,
+		Limits:        x.Limits,
+	}
+	if err = x.Limits.Check(rbxfile.LimitInstances, int64(x.InstanceCount)); err != nil {
+		return nil, err
+	}
+	total := int64(fileHeaderSize)
+	for i, entry := range x.Entries {
+		switch entry.Sig {
+		case ChunkInstance{}.Signature(), ChunkParent{}.Signature(), ChunkEnd{}.Signature():
+		default:
+			if filter != nil && !filter(entry) {
+				continue
+			}
+		}
+		chunk, err := x.readChunk(i, &total)
+		if err != nil {
+			return nil, err
+		}
+		model.Chunks = append(model.Chunks, chunk)
+	}
+	return model, nil
+}
@@ -0,0 +1,168 @@
+package bin
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bkaradzic/go-lz4"
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestLZ4Prefix(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, 4096)
+	for i := range data {
+		// Repetitive enough to produce matches.
+		data[i] = byte(rng.Intn(4))
+	}
+	block, err := lz4.Encode(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block = block[4:]
+	for _, n := range []int{1, 8, 100, 1000, len(data)} {
+		prefix, err := lz4Prefix(block, n)
+		if err != nil {
+			t.Fatalf("%d: %s", n, err)
+		}
+		if len(prefix) < n || !bytes.Equal(prefix, data[:len(prefix)]) {
+			t.Errorf("%d: unexpected prefix", n)
+		}
+	}
+	if _, err := lz4Prefix(block[:len(block)/2], len(data)); err != errShortBlock {
+		t.Errorf("expected short block, got %v", err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	root := recoverTestRoot()
+	root.Instances[0].Children[0].Set("Source", rbxfile.ValueString(strings.Repeat("print(1)\n", 1000)))
+	var buf bytes.Buffer
+	if err := NewSerializer(nil, nil).Serialize(&buf, root); err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		"compressed":   buf.Bytes(),
+		"uncompressed": encodeRecoverTest(t, recoverTestRoot()),
+	}
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.rbxm"))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[file] = b
+	}
+
+	for name, data := range inputs {
+		index, err := NewIndex(bytes.NewReader(data), rbxfile.Limits{})
+		if err != nil {
+			t.Fatalf("%s: index: %s", name, err)
+		}
+		model := new(FormatModel)
+		if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: read: %s", name, err)
+		}
+		if len(index.Entries) != len(model.Chunks) {
+			t.Fatalf("%s: expected %d entries, got %d", name, len(model.Chunks), len(index.Entries))
+		}
+		classes := map[int32]string{}
+		for i, chunk := range model.Chunks {
+			entry := index.Entries[i]
+			if entry.Sig != chunk.Signature() || entry.Compressed != chunk.Compressed() {
+				t.Errorf("%s: entry #%d: unexpected signature or compression", name, i)
+			}
+			switch chunk := chunk.(type) {
+			case *ChunkInstance:
+				classes[chunk.TypeID] = chunk.ClassName
+				if entry.TypeID != chunk.TypeID || entry.ClassName != chunk.ClassName {
+					t.Errorf("%s: entry #%d: expected class %s, got %s", name, i, chunk.ClassName, entry.ClassName)
+				}
+			case *ChunkProperty:
+				if entry.TypeID != chunk.TypeID || entry.PropertyName != chunk.PropertyName || entry.ClassName != classes[chunk.TypeID] {
+					t.Errorf("%s: entry #%d: expected property %s, got %s", name, i, chunk.PropertyName, entry.PropertyName)
+				}
+			default:
+				if entry.TypeID != -1 {
+					t.Errorf("%s: entry #%d: unexpected type ID", name, i)
+				}
+			}
+		}
+	}
+
+	index, err := NewIndex(bytes.NewReader(buf.Bytes()), rbxfile.Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := index.Model(func(entry IndexEntry) bool {
+		return entry.PropertyName == "Name"
+	})
+	if err != nil {
+		t.Fatalf("model: %s", err)
+	}
+	decoded, err := RobloxCodec{}.Decode(model)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	part := decoded.Instances[0].Children[0]
+	if part.Name() != "Part" || len(part.Properties) != 1 || len(part.Children) != 1 {
+		t.Errorf("unexpected instance %s with %d properties", part.Name(), len(part.Properties))
+	}
+}
+
+func TestIndexTotalBytes(t *testing.T) {
+	root := recoverTestRoot()
+	root.Instances[0].Children[0].Set("Source", rbxfile.ValueString(strings.Repeat("print(1)\n", 1000)))
+	var buf bytes.Buffer
+	if err := NewSerializer(nil, nil).Serialize(&buf, root); err != nil {
+		t.Fatal(err)
+	}
+	index, err := NewIndex(bytes.NewReader(buf.Bytes()), rbxfile.Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large := -1
+	for i, entry := range index.Entries {
+		if entry.PropertyName == "Source" {
+			large = i
+		}
+	}
+	if large < 0 {
+		t.Fatal("missing Source chunk")
+	}
+	entry := index.Entries[large]
+
+	// The total is counted per operation, so the chunk can be read repeatedly,
+	// but not as part of a model with the other chunks.
+	index.Limits.MaxTotalBytes = int64(fileHeaderSize) + rawChunkHeaderSize + entry.PayloadSize
+	for n := 0; n < 2; n++ {
+		if _, err := index.ReadChunk(large); err != nil {
+			t.Fatalf("read #%d: %s", n, err)
+		}
+	}
+	if _, err := index.Model(nil); err == nil {
+		t.Fatal("expected total bytes limit error")
+	}
+	index.Limits.MaxTotalBytes = int64(fileHeaderSize)
+	for _, entry := range index.Entries {
+		index.Limits.MaxTotalBytes += rawChunkHeaderSize + entry.PayloadSize
+	}
+	for n := 0; n < 2; n++ {
+		if _, err := index.Model(nil); err != nil {
+			t.Fatalf("model #%d: %s", n, err)
+		}
+	}
+
+	// The chunk size limit is checked while indexing.
+	_, err = NewIndex(bytes.NewReader(buf.Bytes()), rbxfile.Limits{MaxChunkSize: entry.PayloadSize - 1})
+	var lerr rbxfile.ErrLimit
+	if !errors.As(err, &lerr) || lerr.Limit != rbxfile.LimitChunkSize {
+		t.Fatalf("expected chunk size limit error, got %v", err)
+	}
+}
@@ -0,0 +1,417 @@
+package bin
+
+import (
+	"github.com/robloxapi/rbxfile"
+	"sort"
+)
+
+// Layout describes how the instances of a Root were arranged within the
+// FormatModel they were decoded from. A Layout can be used to encode the Root
+// again with the same arrangement, so that a file with few changes produces
+// few differences.
+//
+// To write unmodified chunks byte-for-byte, the FormatModel must be read with
+// PreserveLayout set.
+type Layout struct {
+	// Model is the FormatModel that the Root was decoded from.
+	Model *FormatModel
+
+	// IDs maps each decoded instance to its ID within Model.
+	IDs map[*rbxfile.Instance]int32
+}
+
+// LayoutDecoder is implemented by a Decoder that can produce a Layout.
+type LayoutDecoder interface {
+	DecodeLayout(model *FormatModel) (root *rbxfile.Root, layout *Layout, err error)
+}
+
+// LayoutEncoder is implemented by an Encoder that can encode according to a
+// Layout.
+type LayoutEncoder interface {
+	EncodeLayout(root *rbxfile.Root, layout *Layout) (model *FormatModel, err error)
+}
+
+// DecodeLayout is like Decode, but also returns the Layout of the decoded
+// Root within model.
+func (c RobloxCodec) DecodeLayout(model *FormatModel) (root *rbxfile.Root, layout *Layout, err error) {
+	root, instLookup, err := c.decode(model)
+	if err != nil {
+		return nil, nil, err
+	}
+	layout = &Layout{
+		Model: model,
+		IDs:   make(map[*rbxfile.Instance]int32, len(instLookup)),
+	}
+	for id, inst := range instLookup {
+		if inst != nil {
+			layout.IDs[inst] = id
+		}
+	}
+	return root, layout, nil
+}
+
+// chunkKey identifies a chunk across two FormatModels. Instance chunks are
+// identified by ClassName, and property chunks additionally by PropertyName.
+// Other chunks are identified by signature.
+type chunkKey struct {
+	sig      [4]byte
+	class    string
+	property string
+}
+
+// chunkRank returns the order in which kinds of chunks must appear. Returns
+// -1 for chunks that have no particular order.
+func chunkRank(sig [4]byte) int {
+	switch sig {
+	case ChunkMeta{}.Signature(), ChunkSharedStrings{}.Signature():
+		return 0
+	case ChunkInstance{}.Signature():
+		return 1
+	case ChunkProperty{}.Signature():
+		return 2
+	case ChunkParent{}.Signature():
+		return 3
+	case ChunkEnd{}.Signature():
+		return 4
+	}
+	return -1
+}
+
+// EncodeLayout is like Encode, but arranges the encoded model according to
+// layout. If layout is nil, then EncodeLayout behaves like Encode.
+//
+// Instances retain their IDs, and instance groups retain their type IDs.
+// Chunks are emitted in their original order, with their original
+// compression. Instances, classes, and properties that are not present in
+// layout are added after existing ones, while those that no longer exist are
+// removed. Unknown chunks are carried over unchanged. Shared strings retain
+// their original indexes, and are kept even if they are no longer used.
+//
+// The returned model has PreserveLayout set. If layout.Model was read with
+// PreserveLayout, then chunks whose content is unchanged are written with
+// their original bytes.
+func (c RobloxCodec) EncodeLayout(root *rbxfile.Root, layout *Layout) (model *FormatModel, err error) {
+	if layout == nil || layout.Model == nil {
+		return c.Encode(root)
+	}
+	orig := layout.Model
+
+	model, instList, err := c.encode(root)
+	if err != nil {
+		return nil, err
+	}
+	model.PreserveLayout = true
+
+	// Assign IDs, retaining existing ones.
+	ids := make([]int32, len(instList))
+	used := make(map[int32]bool, len(instList))
+	for i, inst := range instList {
+		ids[i] = -1
+		if id, ok := layout.IDs[inst]; ok && id >= 0 && !used[id] {
+			ids[i] = id
+			used[id] = true
+		}
+	}
+	instanceCount := orig.InstanceCount
+	for id := range used {
+		if uint32(id) >= instanceCount {
+			instanceCount = uint32(id) + 1
+		}
+	}
+	for i := range ids {
+		if ids[i] < 0 {
+			ids[i] = int32(instanceCount)
+			instanceCount++
+		}
+	}
+	remap := func(ref int32) int32 {
+		if ref < 0 || int(ref) >= len(ids) {
+			return ref
+		}
+		return ids[ref]
+	}
+
+	// Index the original chunks.
+	origGroups := map[string]*ChunkInstance{}
+	origClasses := map[int32]string{}
+	var origShared *ChunkSharedStrings
+	var origMeta *ChunkMeta
+	var origParent *ChunkParent
+	for _, chunk := range orig.Chunks {
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			if _, ok := origGroups[chunk.ClassName]; !ok {
+				origGroups[chunk.ClassName] = chunk
+				origClasses[chunk.TypeID] = chunk.ClassName
+			}
+		case *ChunkSharedStrings:
+			if origShared == nil {
+				origShared = chunk
+			}
+		case *ChunkMeta:
+			if origMeta == nil {
+				origMeta = chunk
+			}
+		case *ChunkParent:
+			if origParent == nil {
+				origParent = chunk
+			}
+		}
+	}
+
+	// Assign type IDs, retaining existing ones.
+	typeCount := orig.TypeCount
+	typeIDs := map[int32]int32{}
+	classes := map[int32]string{}
+	for _, chunk := range model.Chunks {
+		if chunk, ok := chunk.(*ChunkInstance); ok {
+			classes[chunk.TypeID] = chunk.ClassName
+			if group, ok := origGroups[chunk.ClassName]; ok {
+				typeIDs[chunk.TypeID] = group.TypeID
+				continue
+			}
+			typeIDs[chunk.TypeID] = int32(typeCount)
+			typeCount++
+		}
+	}
+
+	// Rearrange each chunk according to the new IDs. Retained instances
+	// keep their original order within a group, followed by new instances.
+	perms := map[int32][]int{}
+	for _, chunk := range model.Chunks {
+		chunk, ok := chunk.(*ChunkInstance)
+		if !ok {
+			continue
+		}
+		pos := map[int32]int{}
+		if group, ok := origGroups[chunk.ClassName]; ok {
+			for i, id := range group.InstanceIDs {
+				pos[id] = i
+			}
+		}
+		rank := func(i int) int {
+			if p, ok := pos[remap(chunk.InstanceIDs[i])]; ok {
+				return p
+			}
+			return len(pos) + i
+		}
+		perm := make([]int, len(chunk.InstanceIDs))
+		for i := range perm {
+			perm[i] = i
+		}
+		sort.SliceStable(perm, func(i, j int) bool {
+			return rank(perm[i]) < rank(perm[j])
+		})
+		perms[chunk.TypeID] = perm
+
+		instanceIDs := make([]int32, len(perm))
+		getService := make([]byte, len(perm))
+		for i, j := range perm {
+			instanceIDs[i] = remap(chunk.InstanceIDs[j])
+			getService[i] = chunk.GetService[j]
+		}
+		chunk.InstanceIDs = instanceIDs
+		chunk.GetService = getService
+	}
+
+	var sharedIndexes []uint32
+	fresh := map[chunkKey]Chunk{}
+	var freshKeys []chunkKey
+	for _, chunk := range model.Chunks {
+		key := chunkKey{sig: chunk.Signature()}
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			key.class = chunk.ClassName
+			chunk.TypeID = typeIDs[chunk.TypeID]
+
+		case *ChunkProperty:
+			key.class = classes[chunk.TypeID]
+			key.property = chunk.PropertyName
+			perm := perms[chunk.TypeID]
+			column := chunk.values()
+			properties := NewColumn(chunk.DataType, len(perm))
+			if column == nil || properties == nil {
+				return nil, &ErrInvalidType{Chunk: chunk}
+			}
+			for i, j := range perm {
+				if err = properties.SetValue(i, column.Value(j)); err != nil {
+					return nil, err
+				}
+			}
+			chunk.Column, chunk.Properties = properties, nil
+			chunk.TypeID = typeIDs[chunk.TypeID]
+
+		case *ChunkParent:
+			for i := range chunk.Children {
+				chunk.Children[i] = remap(chunk.Children[i])
+				chunk.Parents[i] = remap(chunk.Parents[i])
+			}
+			if origParent != nil && sameParents(origParent, chunk) {
+				chunk.Children = append([]int32(nil), origParent.Children...)
+				chunk.Parents = append([]int32(nil), origParent.Parents...)
+			}
+
+		case *ChunkMeta:
+			if origMeta != nil && sameMeta(origMeta, chunk) {
+				chunk.Values = append([][2]string(nil), origMeta.Values...)
+			}
+
+		case *ChunkSharedStrings:
+			// Merge shared strings into the original list.
+			values := chunk.Values
+			lookup := map[string]uint32{}
+			if origShared != nil {
+				chunk.Version = origShared.Version
+				chunk.Values = append([]SharedString(nil), origShared.Values...)
+				for i, value := range origShared.Values {
+					if _, ok := lookup[string(value.Value)]; !ok {
+						lookup[string(value.Value)] = uint32(i)
+					}
+				}
+			} else {
+				chunk.Values = nil
+			}
+			sharedIndexes = make([]uint32, len(values))
+			for i, value := range values {
+				index, ok := lookup[string(value.Value)]
+				if !ok {
+					index = uint32(len(chunk.Values))
+					chunk.Values = append(chunk.Values, value)
+					lookup[string(value.Value)] = index
+				}
+				sharedIndexes[i] = index
+			}
+		}
+		if _, ok := fresh[key]; !ok {
+			fresh[key] = chunk
+			freshKeys = append(freshKeys, key)
+		}
+	}
+
+	// Remap values that refer to IDs or shared strings.
+	for _, chunk := range model.Chunks {
+		chunk, ok := chunk.(*ChunkProperty)
+		if !ok {
+			continue
+		}
+		column := chunk.values()
+		for i := 0; i < column.Len(); i++ {
+			switch value := column.Value(i).(type) {
+			case *ValueReference:
+				*value = ValueReference(remap(int32(*value)))
+			case *ValueSharedString:
+				if i := int(*value); i >= 0 && i < len(sharedIndexes) {
+					*value = ValueSharedString(sharedIndexes[i])
+				}
+			}
+		}
+	}
+
+	// Emit chunks in their original order. New chunks are placed before the
+	// first chunk that must come after them.
+	chunks := make([]Chunk, 0, len(model.Chunks))
+	pending := map[chunkKey]bool{}
+	for _, key := range freshKeys {
+		pending[key] = true
+	}
+	origKeys := make([]chunkKey, len(orig.Chunks))
+	for i, chunk := range orig.Chunks {
+		key := chunkKey{sig: chunk.Signature()}
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			key.class = chunk.ClassName
+		case *ChunkProperty:
+			key.class = origClasses[chunk.TypeID]
+			key.property = chunk.PropertyName
+		}
+		origKeys[i] = key
+		delete(pending, key)
+	}
+	flush := func(rank int) {
+		for _, key := range freshKeys {
+			if pending[key] && chunkRank(key.sig) < rank {
+				chunks = append(chunks, fresh[key])
+				delete(pending, key)
+			}
+		}
+	}
+	for i, chunk := range orig.Chunks {
+		key := origKeys[i]
+		if _, ok := chunk.(*ChunkUnknown); ok {
+			chunks = append(chunks, chunk)
+			model.keepRaw(chunk, orig.raw[chunk])
+			continue
+		}
+		flush(chunkRank(key.sig))
+		next, ok := fresh[key]
+		if !ok {
+			// Removed, or already emitted.
+			continue
+		}
+		delete(fresh, key)
+		next.SetCompressed(chunk.Compressed())
+		model.keepRaw(next, orig.raw[chunk])
+		chunks = append(chunks, next)
+	}
+	flush(chunkRank(ChunkEnd{}.Signature()) + 1)
+
+	model.Version = orig.Version
+	model.TypeCount = typeCount
+	model.InstanceCount = instanceCount
+	model.Chunks = chunks
+	model.reserved = orig.reserved
+	return model, nil
+}
+
+// sameParents returns whether b links each instance to the same parent as a,
+// and in the same order for each parent.
+func sameParents(a, b *ChunkParent) bool {
+	if len(a.Children) != len(b.Children) || len(a.Parents) != len(b.Parents) {
+		return false
+	}
+	children := func(c *ChunkParent) map[int32][]int32 {
+		m := map[int32][]int32{}
+		for i, child := range c.Children {
+			if i < len(c.Parents) {
+				m[c.Parents[i]] = append(m[c.Parents[i]], child)
+			}
+		}
+		return m
+	}
+	ca, cb := children(a), children(b)
+	if len(ca) != len(cb) {
+		return false
+	}
+	for parent, list := range ca {
+		other := cb[parent]
+		if len(other) != len(list) {
+			return false
+		}
+		for i := range list {
+			if list[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sameMeta returns whether a and b contain the same metadata.
+func sameMeta(a, b *ChunkMeta) bool {
+	if len(a.Values) != len(b.Values) {
+		return false
+	}
+	m := make(map[string]string, len(a.Values))
+	for _, pair := range a.Values {
+		m[pair[0]] = pair[1]
+	}
+	if len(m) != len(a.Values) {
+		return false
+	}
+	for _, pair := range b.Values {
+		if v, ok := m[pair[0]]; !ok || v != pair[1] {
+			return false
+		}
+	}
+	return true
+}
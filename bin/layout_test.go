@@ -0,0 +1,164 @@
+package bin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+// encodeLayoutTest encodes root with a layout that differs from what
+// RobloxCodec would produce: IDs are reversed, and compression alternates
+// between chunks.
+func encodeLayoutTest(t *testing.T, root *rbxfile.Root) []byte {
+	model, err := RobloxCodec{}.Encode(root)
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	n := int32(model.InstanceCount)
+	reverse := func(id int32) int32 {
+		if id < 0 {
+			return id
+		}
+		return n - 1 - id
+	}
+	for i, chunk := range model.Chunks {
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			for j, id := range chunk.InstanceIDs {
+				chunk.InstanceIDs[j] = reverse(id)
+			}
+		case *ChunkProperty:
+			if refs, ok := chunk.Column.(*ColumnReference); ok {
+				for j, ref := range *refs {
+					(*refs)[j] = ValueReference(reverse(int32(ref)))
+				}
+			}
+		case *ChunkParent:
+			for j := range chunk.Children {
+				chunk.Children[j] = reverse(chunk.Children[j])
+				chunk.Parents[j] = reverse(chunk.Parents[j])
+			}
+		case *ChunkEnd:
+			continue
+		}
+		chunk.SetCompressed(i%2 == 0)
+	}
+	var buf bytes.Buffer
+	if _, err := model.WriteTo(&buf); err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func layoutRoundtrip(t *testing.T, data []byte, modify func(root *rbxfile.Root)) []byte {
+	t.Helper()
+	s := NewSerializer(nil, nil)
+	root, layout, err := s.DeserializeLayout(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if modify != nil {
+		modify(root)
+	}
+	var buf bytes.Buffer
+	if err := s.SerializeLayout(&buf, root, layout); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLayoutUnmodified(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.rbxm"))
+	inputs := map[string][]byte{
+		"layout": encodeLayoutTest(t, recoverTestRoot()),
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[file] = b
+	}
+	for name, data := range inputs {
+		if out := layoutRoundtrip(t, data, nil); !bytes.Equal(out, data) {
+			t.Errorf("%s: expected output to match input", name)
+		}
+	}
+}
+
+// readRaw reads data, retaining raw chunks.
+func readRaw(t *testing.T, data []byte) *FormatModel {
+	model := &FormatModel{PreserveLayout: true}
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	return model
+}
+
+func TestLayoutModified(t *testing.T) {
+	data := encodeLayoutTest(t, recoverTestRoot())
+	out := layoutRoundtrip(t, data, func(root *rbxfile.Root) {
+		root.Instances[0].Children[0].Set("Name", rbxfile.ValueString("Changed"))
+	})
+
+	a, b := readRaw(t, data), readRaw(t, out)
+	if len(a.Chunks) != len(b.Chunks) {
+		t.Fatalf("expected %d chunks, got %d", len(a.Chunks), len(b.Chunks))
+	}
+	var changed []string
+	for i := range a.Chunks {
+		ra, rb := a.raw[a.Chunks[i]], b.raw[b.Chunks[i]]
+		if ra.signature != rb.signature {
+			t.Fatalf("chunk #%d: expected %s, got %s", i, ra.signature, rb.signature)
+		}
+		if ra.compressed != rb.compressed {
+			t.Errorf("chunk #%d: compression changed", i)
+		}
+		if !bytes.Equal(ra.data, rb.data) {
+			changed = append(changed, string(ra.signature[:]))
+			if prop, ok := b.Chunks[i].(*ChunkProperty); !ok || prop.PropertyName != "Name" {
+				t.Errorf("chunk #%d: unexpected change", i)
+			}
+		}
+	}
+	if len(changed) != 1 {
+		t.Errorf("expected 1 changed chunk, got %v", changed)
+	}
+}
+
+func TestLayoutAddRemove(t *testing.T) {
+	data := encodeLayoutTest(t, recoverTestRoot())
+	out := layoutRoundtrip(t, data, func(root *rbxfile.Root) {
+		part := root.Instances[0].Children[0]
+		part.Children[0].SetParent(nil)
+		model := rbxfile.NewInstance("Model", part)
+		model.Set("Name", rbxfile.ValueString("Model"))
+		model.Set("PrimaryPart", rbxfile.ValueReference{Instance: part})
+	})
+
+	root, layout, err := NewSerializer(nil, nil).DeserializeLayout(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	workspace := root.Instances[0]
+	part := workspace.Children[0]
+	if len(part.Children) != 1 || part.Children[0].ClassName != "Model" {
+		t.Fatal("unexpected tree structure")
+	}
+	model := part.Children[0]
+	if model.Get("PrimaryPart") != (rbxfile.ValueReference{Instance: part}) {
+		t.Error("expected reference to Part")
+	}
+
+	// Retained instances keep their IDs, while the new instance is added
+	// after them.
+	if layout.IDs[workspace] != 2 || layout.IDs[part] != 1 || layout.IDs[model] != 3 {
+		t.Errorf("unexpected IDs %v", layout.IDs)
+	}
+	if layout.Model.InstanceCount != 4 || layout.Model.TypeCount != 4 {
+		t.Errorf("unexpected counts %d, %d", layout.Model.InstanceCount, layout.Model.TypeCount)
+	}
+}
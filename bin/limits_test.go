@@ -0,0 +1,107 @@
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/xml"
+	"testing"
+)
+
+func expectLimit(t *testing.T, err error, limit rbxfile.Limit) {
+	t.Helper()
+	var lerr rbxfile.ErrLimit
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected %s limit error, got %v", limit, err)
+	}
+	if lerr.Limit != limit {
+		t.Fatalf("expected %s limit error, got %s", limit, lerr.Limit)
+	}
+}
+
+func TestLimitsChunkHeader(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString(RobloxSig + BinaryMarker + BinaryHeader)
+	binary.Write(&b, binary.LittleEndian, uint16(0))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	binary.Write(&b, binary.LittleEndian, uint64(0))
+	// Chunk that claims a very large uncompressed payload.
+	b.WriteString("META")
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	binary.Write(&b, binary.LittleEndian, uint32(0xFFFFFFF0))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+
+	model := &FormatModel{Limits: rbxfile.Limits{MaxChunkSize: 1 << 20}}
+	_, err := model.ReadFrom(bytes.NewReader(b.Bytes()))
+	expectLimit(t, err, rbxfile.LimitChunkSize)
+
+	model = &FormatModel{Limits: rbxfile.Limits{MaxTotalBytes: 1 << 20}}
+	_, err = model.ReadFrom(bytes.NewReader(b.Bytes()))
+	expectLimit(t, err, rbxfile.LimitTotalBytes)
+
+	model = &FormatModel{Recover: true, Limits: rbxfile.Limits{MaxTotalBytes: 8}}
+	_, err = model.ReadFrom(bytes.NewReader(b.Bytes()))
+	expectLimit(t, err, rbxfile.LimitTotalBytes)
+}
+
+func TestLimitsBinary(t *testing.T) {
+	data := encodeRecoverTest(t, recoverTestRoot())
+
+	tests := []struct {
+		limits rbxfile.Limits
+		limit  rbxfile.Limit
+	}{
+		{rbxfile.Limits{MaxChunkSize: 8}, rbxfile.LimitChunkSize},
+		{rbxfile.Limits{MaxTotalBytes: int64(len(data)) / 2}, rbxfile.LimitTotalBytes},
+		{rbxfile.Limits{MaxInstances: 2}, rbxfile.LimitInstances},
+		{rbxfile.Limits{MaxProperties: 3}, rbxfile.LimitProperties},
+		{rbxfile.Limits{MaxStringLength: 5}, rbxfile.LimitStringLength},
+		{rbxfile.Limits{MaxDepth: 2}, rbxfile.LimitDepth},
+	}
+	for _, test := range tests {
+		s := NewSerializer(nil, nil)
+		s.Limits = test.limits
+		_, err := s.Deserialize(bytes.NewReader(data))
+		expectLimit(t, err, test.limit)
+	}
+
+	s := NewSerializer(nil, nil)
+	s.Limits = rbxfile.Limits{
+		MaxChunkSize:    1 << 10,
+		MaxTotalBytes:   int64(len(data)) * 2,
+		MaxInstances:    3,
+		MaxProperties:   4,
+		MaxStringLength: 9,
+		MaxDepth:        3,
+	}
+	if _, err := s.Deserialize(bytes.NewReader(data)); err != nil {
+		t.Fatalf("unexpected error within limits: %s", err)
+	}
+}
+
+func TestLimitsXML(t *testing.T) {
+	var b bytes.Buffer
+	if err := xml.Serialize(&b, nil, recoverTestRoot()); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	data := b.Bytes()
+
+	tests := []struct {
+		limits rbxfile.Limits
+		limit  rbxfile.Limit
+	}{
+		{rbxfile.Limits{MaxTotalBytes: int64(len(data)) / 2}, rbxfile.LimitTotalBytes},
+		{rbxfile.Limits{MaxInstances: 2}, rbxfile.LimitInstances},
+		{rbxfile.Limits{MaxProperties: 3}, rbxfile.LimitProperties},
+		{rbxfile.Limits{MaxStringLength: 5}, rbxfile.LimitStringLength},
+		{rbxfile.Limits{MaxDepth: 4}, rbxfile.LimitDepth},
+	}
+	for _, test := range tests {
+		s := NewSerializer(nil, nil)
+		s.Limits = test.limits
+		_, err := s.Deserialize(bytes.NewReader(data))
+		expectLimit(t, err, test.limit)
+	}
+}
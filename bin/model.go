@@ -2,10 +2,12 @@ package bin
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/bkaradzic/go-lz4"
+	"github.com/robloxapi/rbxfile"
 	"io"
 	"io/ioutil"
 )
@@ -170,6 +172,21 @@ invalid:
 	panic("invalid type")
 }
 
+// ensure fails if the underlying reader is known to have less than n bytes
+// remaining. This is used to avoid allocating based on a corrupted length.
+func (f *formatReader) ensure(n int64) (failed bool) {
+	if f.err != nil {
+		return true
+	}
+
+	if r, ok := f.r.(interface{ Len() int }); ok && int64(r.Len()) < n {
+		f.err = io.ErrUnexpectedEOF
+		return true
+	}
+
+	return false
+}
+
 func (f *formatReader) readString(data *string) (failed bool) {
 	if f.err != nil {
 		return true
@@ -180,6 +197,10 @@ func (f *formatReader) readString(data *string) (failed bool) {
 		return true
 	}
 
+	if f.ensure(int64(length)) {
+		return true
+	}
+
 	s := make([]byte, length)
 	if f.read(s) {
 		return true
@@ -325,17 +346,120 @@ type FormatModel struct {
 	// be cleared and populated when calling either ReadFrom and WriteTo.
 	// Codecs may also clear and populate this when decoding or encoding.
 	Warnings []error
+
+	// If Recover is true, then ReadFrom attempts to salvage as much data as
+	// possible from a corrupted file. When a chunk cannot be read, it is
+	// skipped, and reading resumes at the next recognizable chunk signature.
+	// Codecs that support recovery will likewise skip chunks that cannot be
+	// decoded, rather than failing entirely. Strict has no effect on chunk
+	// errors while Recover is true.
+	Recover bool
+
+	// Damaged is a list of chunks that were discarded while recovering. This
+	// will be cleared and populated when calling ReadFrom with Recover set.
+	// Codecs may also append to this when decoding.
+	Damaged []DamagedChunk
+
+	// Limits bounds the resources used by ReadFrom. When a limit is exceeded,
+	// ReadFrom returns a rbxfile.ErrLimit, regardless of Strict or Recover.
+	// Codecs may also honor Limits when decoding.
+	Limits rbxfile.Limits
+
+	// If PreserveLayout is true, then ReadFrom retains the original bytes of
+	// each chunk, as well as the reserved space of the header. When writing,
+	// WriteTo emits the original bytes of any chunk whose content and
+	// compression are unchanged, rather than compressing the chunk again. An
+	// unmodified model is therefore written byte-for-byte as it was read.
+	PreserveLayout bool
+
+	// If LazyProperties is true, then ReadFrom does not decode the values of
+	// each ChunkProperty. Instead, the undecoded values are retained until
+	// ChunkProperty.LoadProperties is called, which RobloxCodec does as
+	// needed. Errors in the values are therefore not detected while reading.
+	LazyProperties bool
+
+	// Context, if not nil, is checked while reading and writing, as well as
+	// by RobloxCodec while decoding. Once the context is done, the operation
+	// stops and returns the context's error.
+	Context context.Context
+
+	// Progress, if not nil, is called after each chunk is read or written,
+	// and by RobloxCodec after each group of instances is decoded.
+	Progress rbxfile.ProgressFunc
+
+	// progress is the current progress of reading, writing or decoding.
+	progress rbxfile.Progress
+
+	// raw maps a chunk to the raw chunk it was read from.
+	raw map[Chunk]*rawChunk
+
+	// reserved is the reserved space of the header.
+	reserved uint64
+}
+
+// keepRaw retains the raw chunk that chunk was read from, if PreserveLayout
+// is set.
+func (f *FormatModel) keepRaw(chunk Chunk, raw *rawChunk) {
+	if !f.PreserveLayout || raw == nil {
+		return
+	}
+	if f.raw == nil {
+		f.raw = map[Chunk]*rawChunk{}
+	}
+	f.raw[chunk] = raw
+}
+
+// checkContext returns the error of Context, if the context is done.
+func (f *FormatModel) checkContext() error {
+	if f.Context == nil {
+		return nil
+	}
+	return f.Context.Err()
+}
+
+// report calls Progress with the current progress.
+func (f *FormatModel) report() {
+	if f.Progress != nil {
+		f.Progress(f.progress)
+	}
+}
+
+// reportChunk records that a chunk ending at byte n was processed.
+func (f *FormatModel) reportChunk(n int64) {
+	f.progress.Bytes = n
+	f.progress.Chunks++
+	f.report()
+}
+
+// contextReader is a reader that fails once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
 
 // ReadFrom decodes data from r into the FormatModel.
 //
 // If an error occurs while reading a chunk, the error is emitted as a
-// ErrChunk to FormatModel.Warnings, unless FormatModel.Strict is true.
+// ErrChunk to FormatModel.Warnings, unless FormatModel.Strict is true. If
+// FormatModel.Recover is true, damaged chunks are instead skipped and recorded
+// in FormatModel.Damaged.
 func (f *FormatModel) ReadFrom(r io.Reader) (n int64, err error) {
 	if r == nil {
 		return 0, errors.New("reader is nil")
 	}
 
+	f.progress = rbxfile.Progress{}
+	if f.Context != nil {
+		r = contextReader{ctx: f.Context, r: r}
+	}
+
 	fr := &formatReader{r: r}
 
 	sig := make([]byte, len(RobloxSig+BinaryMarker))
@@ -375,6 +499,8 @@ func (f *FormatModel) ReadFrom(r io.Reader) (n int64, err error) {
 	// reuse space from previous slices
 	f.Warnings = f.Warnings[:0]
 	f.Chunks = f.Chunks[:0]
+	f.Damaged = f.Damaged[:0]
+	f.raw = nil
 
 	if fr.readNumber(binary.LittleEndian, &f.TypeCount) {
 		return fr.end()
@@ -383,6 +509,9 @@ func (f *FormatModel) ReadFrom(r io.Reader) (n int64, err error) {
 	if fr.readNumber(binary.LittleEndian, &f.InstanceCount) {
 		return fr.end()
 	}
+	if fr.err = f.Limits.Check(rbxfile.LimitInstances, int64(f.InstanceCount)); fr.err != nil {
+		return fr.end()
+	}
 
 	var reserved uint64
 	if fr.readNumber(binary.LittleEndian, &reserved) {
@@ -391,22 +520,28 @@ func (f *FormatModel) ReadFrom(r io.Reader) (n int64, err error) {
 	if reserved != 0 {
 		f.Warnings = append(f.Warnings, WarnReserveNonZero)
 	}
+	if f.PreserveLayout {
+		f.reserved = reserved
+	}
+
+	if f.Recover {
+		f.recoverChunks(fr)
+		return fr.end()
+	}
+
+	total := fr.n
 
 loop:
 	for {
 		rawChunk := new(rawChunk)
-		if rawChunk.ReadFrom(fr) {
+		if rawChunk.ReadFrom(fr, f.Limits, total) {
 			return fr.end()
 		}
+		total += rawChunkHeaderSize + int64(len(rawChunk.payload))
+		f.reportChunk(fr.n)
 
-		newChunk := chunkGenerators(f.Version, rawChunk.signature)
-		if newChunk == nil {
-			newChunk = newChunkUnknown
-		}
-		chunk := newChunk()
-		chunk.SetCompressed(rawChunk.compressed)
-
-		if _, err := chunk.ReadFrom(bytes.NewReader(rawChunk.payload)); err != nil {
+		chunk, err := decodeRawChunk(f.Version, rawChunk, f.LazyProperties)
+		if err != nil {
 			err = ErrChunk{Sig: rawChunk.signature, Err: err}
 			if f.Strict {
 				fr.err = err
@@ -417,6 +552,7 @@ loop:
 		}
 
 		f.Chunks = append(f.Chunks, chunk)
+		f.keepRaw(chunk, rawChunk)
 
 		switch chunk := chunk.(type) {
 		case *ChunkUnknown:
@@ -444,6 +580,7 @@ func (f *FormatModel) WriteTo(w io.Writer) (n int64, err error) {
 	}
 
 	f.Warnings = f.Warnings[:0]
+	f.progress = rbxfile.Progress{}
 
 	fw := &formatWriter{w: w}
 
@@ -464,11 +601,19 @@ func (f *FormatModel) WriteTo(w io.Writer) (n int64, err error) {
 	}
 
 	// reserved
-	if fw.writeNumber(binary.LittleEndian, uint64(0)) {
+	var reserved uint64
+	if f.PreserveLayout {
+		reserved = f.reserved
+	}
+	if fw.writeNumber(binary.LittleEndian, reserved) {
 		return fw.end()
 	}
 
 	for i, chunk := range f.Chunks {
+		if fw.err = f.checkContext(); fw.err != nil {
+			return fw.end()
+		}
+
 		if !validChunk(f.Version, chunk.Signature()) {
 			f.Warnings = append(f.Warnings, &ChunkUnknown{
 				Sig: chunk.Signature(),
@@ -500,9 +645,20 @@ func (f *FormatModel) WriteTo(w io.Writer) (n int64, err error) {
 
 		rawChunk.payload = buf.Bytes()
 
+		if f.PreserveLayout {
+			// Reuse the original bytes of an unchanged chunk.
+			if orig, ok := f.raw[chunk]; ok && orig.data != nil &&
+				orig.signature == rawChunk.signature &&
+				orig.compressed == rawChunk.compressed &&
+				bytes.Equal(orig.payload, rawChunk.payload) {
+				rawChunk = orig
+			}
+		}
+
 		if rawChunk.WriteTo(fw) {
 			return fw.end()
 		}
+		f.reportChunk(fw.n)
 	}
 
 	return fw.end()
@@ -537,10 +693,20 @@ type rawChunk struct {
 	signature  [4]byte
 	compressed bool
 	payload    []byte
+
+	// reserved and data are the reserved field and the body of the chunk as
+	// they were read, where data is compressed if the chunk is compressed.
+	// If data is not nil, then WriteTo writes the chunk exactly as it was
+	// read.
+	reserved uint32
+	data     []byte
 }
 
 // Reads out a raw chunk from a stream, decompressing the chunk if necessary.
-func (c *rawChunk) ReadFrom(fr *formatReader) bool {
+//
+// The limits are checked against the length of the payload before it is
+// allocated. total is the number of bytes processed before the chunk.
+func (c *rawChunk) ReadFrom(fr *formatReader, limits rbxfile.Limits, total int64) bool {
 	if fr.read(c.signature[:]) {
 		return true
 	}
@@ -555,8 +721,17 @@ func (c *rawChunk) ReadFrom(fr *formatReader) bool {
 		return true
 	}
 
-	var reserved uint32
-	if fr.readNumber(binary.LittleEndian, &reserved) {
+	if fr.readNumber(binary.LittleEndian, &c.reserved) {
+		return true
+	}
+
+	if fr.err = limits.Check(rbxfile.LimitChunkSize, int64(decompressedLength)); fr.err != nil {
+		return true
+	}
+	if fr.err = limits.Check(rbxfile.LimitChunkSize, int64(compressedLength)); fr.err != nil {
+		return true
+	}
+	if fr.err = limits.Check(rbxfile.LimitTotalBytes, total+rawChunkHeaderSize+int64(decompressedLength)); fr.err != nil {
 		return true
 	}
 
@@ -567,12 +742,13 @@ func (c *rawChunk) ReadFrom(fr *formatReader) bool {
 		if fr.read(c.payload) {
 			return true
 		}
+		c.data = c.payload
 	} else {
 		c.compressed = true
 
 		// Prepare compressed data for reading by lz4, which requires the
 		// uncompressed length before the compressed data.
-		compressedData := make([]byte, compressedLength+4)
+		compressedData := make([]byte, int64(compressedLength)+4)
 		binary.LittleEndian.PutUint32(compressedData, decompressedLength)
 
 		if fr.read(compressedData[4:]) {
@@ -587,17 +763,58 @@ func (c *rawChunk) ReadFrom(fr *formatReader) bool {
 			fr.err = fmt.Errorf("lz4: %s", err.Error())
 			return true
 		}
+		c.data = compressedData[4:]
 	}
 
 	return false
 }
 
+// decodeRawChunk decodes the payload of a raw chunk into a Chunk of the
+// corresponding signature. If lazy is true, then the values of a
+// ChunkProperty are not decoded.
+func decodeRawChunk(version uint16, raw *rawChunk, lazy bool) (chunk Chunk, err error) {
+	newChunk := chunkGenerators(version, raw.signature)
+	if newChunk == nil {
+		newChunk = newChunkUnknown
+	}
+	chunk = newChunk()
+	switch chunk := chunk.(type) {
+	case *ChunkUnknown:
+		chunk.Sig = raw.signature
+	case *ChunkProperty:
+		chunk.lazy = lazy
+	}
+	chunk.SetCompressed(raw.compressed)
+
+	if _, err = chunk.ReadFrom(bytes.NewReader(raw.payload)); err != nil {
+		return chunk, err
+	}
+	return chunk, nil
+}
+
 // Writes a raw chunk payload to a stream, compressing if necessary.
 func (c *rawChunk) WriteTo(fw *formatWriter) bool {
 	if fw.write(c.signature[:]) {
 		return true
 	}
 
+	if c.data != nil {
+		compressedLength := uint32(len(c.data))
+		if !c.compressed {
+			compressedLength = 0
+		}
+		if fw.writeNumber(binary.LittleEndian, compressedLength) {
+			return true
+		}
+		if fw.writeNumber(binary.LittleEndian, uint32(len(c.payload))) {
+			return true
+		}
+		if fw.writeNumber(binary.LittleEndian, c.reserved) {
+			return true
+		}
+		return fw.write(c.data)
+	}
+
 	if c.compressed {
 		var compressedData []byte
 		compressedData, fw.err = lz4.Encode(compressedData, c.payload)
@@ -779,6 +996,10 @@ func (c *ChunkInstance) ReadFrom(r io.Reader) (n int64, err error) {
 		return fr.end()
 	}
 
+	if fr.ensure(int64(groupLength) * 4) {
+		return fr.end()
+	}
+
 	c.InstanceIDs = make([]int32, groupLength)
 	if groupLength > 0 {
 		raw := make([]byte, groupLength*4)
@@ -954,6 +1175,10 @@ func (c *ChunkParent) ReadFrom(r io.Reader) (n int64, err error) {
 		return fr.end()
 	}
 
+	if fr.ensure(int64(instanceCount) * 8) {
+		return fr.end()
+	}
+
 	c.Children = make([]int32, instanceCount)
 	if instanceCount > 0 {
 		raw := make([]byte, instanceCount*4)
@@ -1061,10 +1286,23 @@ type ChunkProperty struct {
 	// DataType is a number indicating the type of the property.
 	DataType Type
 
-	// Properties is a list of Values of the given DataType. Each value in the
-	// array corresponds to the property of an instance in the specified
-	// group.
+	// Column contains the values of the given DataType. Each value in the
+	// column corresponds to the property of an instance in the specified
+	// group. If the chunk was read lazily, then Column is nil until
+	// LoadProperties is called.
+	Column Column
+
+	// Properties is a list of Values of the given DataType. It remains for
+	// compatibility with code that builds chunks from Values, and is used in
+	// place of Column when Column is nil. ReadFrom and RobloxCodec populate
+	// Column instead, so Values should be used to read the values of a
+	// chunk.
 	Properties []Value
+
+	// lazy determines whether ReadFrom retains the undecoded values in raw,
+	// rather than decoding them into Column.
+	lazy bool
+	raw  []byte
 }
 
 func newChunkProperty() Chunk {
@@ -1103,19 +1341,26 @@ func (c *ChunkProperty) ReadFrom(r io.Reader) (n int64, err error) {
 		return fr.end()
 	}
 
-	newValue, ok := valueGenerators[c.DataType]
-	if !ok {
+	column := NewColumn(c.DataType, 0)
+	if column == nil {
 		fr.err = &ErrInvalidType{Chunk: c, Bytes: rawBytes}
 		return fr.end()
 	}
 
-	c.Properties, fr.err = newValue().FromArrayBytes(rawBytes)
-	if fr.err != nil {
+	c.Properties = nil
+	if c.lazy {
+		c.Column = nil
+		c.raw = rawBytes
+		return fr.end()
+	}
+
+	if fr.err = column.FromArrayBytes(rawBytes); fr.err != nil {
 		errBytes := make([]byte, len(rawBytes))
 		copy(errBytes, rawBytes)
 		fr.err = ErrValue{Type: c.DataType, Bytes: errBytes, Err: fr.err}
 		return fr.end()
 	}
+	c.Column = column
 
 	return fr.end()
 }
@@ -1135,14 +1380,19 @@ func (c *ChunkProperty) WriteTo(w io.Writer) (n int64, err error) {
 		return fw.end()
 	}
 
-	newValue, ok := valueGenerators[c.DataType]
-	if !ok {
+	if c.raw != nil {
+		fw.write(c.raw)
+		return fw.end()
+	}
+
+	column := c.values()
+	if column == nil || column.Type() != c.DataType {
 		fw.err = &ErrInvalidType{Chunk: c}
 		return fw.end()
 	}
 
 	var rawBytes []byte
-	if rawBytes, fw.err = newValue().ArrayBytes(c.Properties); fw.err != nil {
+	if rawBytes, fw.err = column.ArrayBytes(); fw.err != nil {
 		return fw.end()
 	}
 
@@ -1151,6 +1401,55 @@ func (c *ChunkProperty) WriteTo(w io.Writer) (n int64, err error) {
 	return fw.end()
 }
 
+// values returns the values of the chunk as a Column, using Properties if
+// Column is nil. Returns nil if DataType is invalid.
+func (c *ChunkProperty) values() Column {
+	if c.Column != nil {
+		return c.Column
+	}
+	if c.Properties != nil {
+		if _, ok := valueGenerators[c.DataType]; !ok {
+			return nil
+		}
+		return &ValueColumn{DataType: c.DataType, Values: c.Properties}
+	}
+	return NewColumn(c.DataType, 0)
+}
+
+// Values returns the values of the chunk as a list of Values, which point
+// into Column. If Column is nil, then Properties is returned.
+func (c *ChunkProperty) Values() []Value {
+	if c.Column == nil {
+		return c.Properties
+	}
+	a := make([]Value, c.Column.Len())
+	for i := range a {
+		a[i] = c.Column.Value(i)
+	}
+	return a
+}
+
+// LoadProperties decodes the values of a chunk that was read with
+// FormatModel.LazyProperties set, populating Column. Does nothing if the
+// values have already been decoded.
+func (c *ChunkProperty) LoadProperties() error {
+	if c.raw == nil {
+		return nil
+	}
+
+	column := NewColumn(c.DataType, 0)
+	if column == nil {
+		return &ErrInvalidType{Chunk: c, Bytes: c.raw}
+	}
+
+	if err := column.FromArrayBytes(c.raw); err != nil {
+		return ErrValue{Type: c.DataType, Bytes: c.raw, Err: err}
+	}
+	c.Column = column
+	c.raw = nil
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////
 
 // ChunkMeta is a Chunk that contains file metadata.
@@ -1193,6 +1492,10 @@ func (c *ChunkMeta) ReadFrom(r io.Reader) (n int64, err error) {
 	if fr.readNumber(binary.LittleEndian, &size) {
 		return fr.end()
 	}
+	// Each pair contains at least two string lengths.
+	if fr.ensure(int64(size) * 8) {
+		return fr.end()
+	}
 	c.Values = make([][2]string, int(size))
 
 	for i := range c.Values {
@@ -1270,6 +1573,10 @@ func (c *ChunkSharedStrings) ReadFrom(r io.Reader) (n int64, err error) {
 	if fr.readNumber(binary.LittleEndian, &length) {
 		return fr.end()
 	}
+	// Each value contains at least a hash and a string length.
+	if fr.ensure(int64(length) * 20) {
+		return fr.end()
+	}
 	c.Values = make([]SharedString, int(length))
 
 	for i := range c.Values {
@@ -319,7 +319,7 @@ func TestFormatModel_ReadFrom(t *testing.T) {
 	}
 	if len(f.Warnings) == 0 {
 		t.Error("expected warning (bad chunk sig)")
-	} else if _, ok := f.Warnings[0].(WarnUnknownChunk); !ok {
+	} else if _, ok := f.Warnings[0].(*ChunkUnknown); !ok {
 		t.Error("expected warning (bad chunk sig), got:", f.Warnings[0])
 	}
 
@@ -344,8 +344,19 @@ func TestFormatModel_ReadFrom(t *testing.T) {
 		}
 	}
 
+	// A chunk that fails to decode is a warning, unless Strict is set.
+	if err := readFrom(f, b, "INST", 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0); err != io.EOF {
+		t.Error("expected error (no chunk after empty inst chunk), got:", err)
+	}
+	if len(f.Warnings) == 0 {
+		t.Error("expected warning (empty inst chunk)")
+	} else if _, ok := f.Warnings[0].(ErrChunk); !ok {
+		t.Error("expected warning (empty inst chunk), got:", f.Warnings[0])
+	}
+
+	f.Strict = true
 	if err, ok := readFrom(f, b, "INST", 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0).(ErrChunk); !ok {
-		t.Error("expected error (empty inst chunk), got:", err)
+		t.Errorf("expected error (empty inst chunk), got: %#v", err)
 	} else {
 		if string(err.Sig[:]) != "INST" {
 			t.Error("expected INST chunk error, got:", string(err.Sig[:]))
@@ -437,9 +448,9 @@ func TestFormatModel_WriteTo(t *testing.T) {
 			t.Error("expected warning (end chunk not last)")
 		}
 		for _, warning := range f.Warnings {
-			if warning, ok := warning.(WarnUnknownChunk); ok {
-				if string(warning[:]) != "TEST" {
-					t.Error("unexpected signature (unknown chunk)", [4]byte(warning))
+			if warning, ok := warning.(*ChunkUnknown); ok {
+				if string(warning.Sig[:]) != "TEST" {
+					t.Error("unexpected signature (unknown chunk)", warning.Sig)
 				}
 				goto okay
 			}
@@ -0,0 +1,357 @@
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/robloxapi/rbxfile"
+	"io"
+	"sort"
+)
+
+// Size of the header that precedes the payload of each chunk.
+const rawChunkHeaderSize = 16
+
+// Largest ratio between the decompressed and compressed lengths of an LZ4
+// block.
+const maxCompressionRatio = 255
+
+var (
+	WarnEndChunkMissing = errors.New("end chunk is missing")
+)
+
+// DamagedChunk describes a chunk that was discarded while recovering a
+// corrupted file.
+type DamagedChunk struct {
+	// Offset is the location of the chunk, in bytes from the start of the
+	// stream. It is -1 if the chunk was discarded by a codec, rather than
+	// while reading the format.
+	Offset int64
+
+	// Sig is the signature of the chunk. It will be zero if not enough bytes
+	// remained in the stream to read a signature.
+	Sig [4]byte
+
+	// Chunk is the chunk as far as it could be decoded. Fields that appear
+	// before the point of failure, such as ChunkProperty.TypeID and
+	// ChunkProperty.PropertyName, are usually intact. Chunk is nil if the
+	// payload of the chunk could not be read at all.
+	Chunk Chunk
+
+	// Err is the error that caused the chunk to be discarded.
+	Err error
+}
+
+// probeRawChunk returns whether data contains a plausible chunk header at
+// position i. If known is true, then only chunks with a signature recognized
+// by the format version are accepted.
+func (f *FormatModel) probeRawChunk(data []byte, i int, known bool) bool {
+	if i < 0 || len(data)-i < rawChunkHeaderSize {
+		return false
+	}
+	h := data[i : i+rawChunkHeaderSize]
+	var sig [4]byte
+	copy(sig[:], h[0:4])
+	if known && !validChunk(f.Version, sig) {
+		return false
+	}
+	compressedLength := binary.LittleEndian.Uint32(h[4:8])
+	decompressedLength := binary.LittleEndian.Uint32(h[8:12])
+	if reserved := binary.LittleEndian.Uint32(h[12:16]); reserved != 0 {
+		return false
+	}
+	remaining := uint64(len(data) - i - rawChunkHeaderSize)
+	if compressedLength == 0 {
+		return uint64(decompressedLength) <= remaining
+	}
+	if uint64(compressedLength) > remaining {
+		return false
+	}
+	return uint64(decompressedLength) <= uint64(compressedLength)*maxCompressionRatio+rawChunkHeaderSize
+}
+
+// resync returns the position of the next plausible chunk with a recognized
+// signature, starting at i. Returns the length of data if no chunk is found.
+func (f *FormatModel) resync(data []byte, i int) int {
+	for ; i+rawChunkHeaderSize <= len(data); i++ {
+		if !f.probeRawChunk(data, i, true) {
+			continue
+		}
+		// Ensure that the chunk can actually be decompressed before
+		// committing to it.
+		raw := new(rawChunk)
+		if !raw.ReadFrom(&formatReader{r: bytes.NewReader(data[i:])}, f.Limits, 0) {
+			return i
+		}
+	}
+	return len(data)
+}
+
+// recoverChunks reads the remaining chunks from fr, skipping over damaged
+// chunks instead of failing.
+func (f *FormatModel) recoverChunks(fr *formatReader) {
+	base := fr.n
+	r := fr.r
+	if max := f.Limits.MaxTotalBytes; max > 0 {
+		// Read one more byte than allowed so that exceeding the limit can be
+		// detected.
+		fr.r = io.LimitReader(r, max-base+1)
+	}
+	data, failed := fr.readall()
+	fr.r = r
+	if failed {
+		return
+	}
+	if fr.err = f.Limits.Check(rbxfile.LimitTotalBytes, fr.n); fr.err != nil {
+		return
+	}
+	total := base
+
+	damage := func(i int, chunk Chunk, err error) {
+		d := DamagedChunk{Offset: base + int64(i), Chunk: chunk, Err: err}
+		copy(d.Sig[:], data[i:])
+		f.Damaged = append(f.Damaged, d)
+		f.Warnings = append(f.Warnings, ErrChunk{Sig: d.Sig, Err: err})
+	}
+
+	for i := 0; i < len(data); {
+		if fr.err = f.checkContext(); fr.err != nil {
+			return
+		}
+		if !f.probeRawChunk(data, i, false) {
+			if len(data)-i < rawChunkHeaderSize {
+				damage(i, nil, errors.New("truncated chunk header"))
+			} else {
+				damage(i, nil, errors.New("malformed chunk header"))
+			}
+			i = f.resync(data, i+1)
+			continue
+		}
+
+		r := &formatReader{r: bytes.NewReader(data[i:])}
+		raw := new(rawChunk)
+		if raw.ReadFrom(r, f.Limits, total) {
+			if _, ok := r.err.(rbxfile.ErrLimit); ok {
+				fr.err = r.err
+				return
+			}
+			damage(i, nil, r.err)
+			i = f.resync(data, i+1)
+			continue
+		}
+		next := i + int(r.n)
+		total += rawChunkHeaderSize + int64(len(raw.payload))
+		f.reportChunk(base + int64(next))
+
+		chunk, err := decodeRawChunk(f.Version, raw, false)
+		if err != nil {
+			damage(i, chunk, err)
+			// The length of the chunk may itself be corrupted. Only trust it
+			// if another chunk begins where this one ends.
+			if next < len(data) && !f.probeRawChunk(data, next, true) {
+				next = f.resync(data, i+1)
+			}
+			i = next
+			continue
+		}
+
+		f.Chunks = append(f.Chunks, chunk)
+		f.keepRaw(chunk, raw)
+
+		switch chunk := chunk.(type) {
+		case *ChunkUnknown:
+			f.Warnings = append(f.Warnings, chunk)
+		case *ChunkEnd:
+			if chunk.Compressed() {
+				f.Warnings = append(f.Warnings, WarnEndChunkCompressed)
+			}
+
+			if !bytes.Equal(chunk.Content, []byte("</roblox>")) {
+				f.Warnings = append(f.Warnings, WarnEndChunkContent)
+			}
+			return
+		}
+		i = next
+	}
+
+	f.Warnings = append(f.Warnings, WarnEndChunkMissing)
+}
+
+// LostProperty describes a property that was lost from every instance of a
+// class.
+type LostProperty struct {
+	// ClassName is the class of the instances that lost the property. It is
+	// empty if the class could not be determined.
+	ClassName string
+
+	// PropertyName is the name of the property.
+	PropertyName string
+}
+
+// LostInstance describes an instance that was lost or displaced.
+type LostInstance struct {
+	// ID is the instance's number within the file.
+	ID int32
+
+	// ClassName is the class of the instance. It is empty if the class could
+	// not be determined.
+	ClassName string
+}
+
+// RecoveryReport describes the data that was lost while recovering a
+// corrupted file.
+type RecoveryReport struct {
+	// Chunks is the list of chunks that were discarded.
+	Chunks []DamagedChunk
+
+	// Classes is a sorted list of the class names of discarded instance
+	// chunks. Classes whose name could not be read are not included.
+	Classes []string
+
+	// Properties is a list of discarded property chunks, sorted by class and
+	// property name.
+	Properties []LostProperty
+
+	// Instances is a list of instances, sorted by ID, that were lost. This
+	// includes the instances listed by each discarded instance chunk, as far
+	// as the chunk could be read, and instances referred to by a parent chunk,
+	// but whose instance chunk was discarded.
+	Instances []LostInstance
+
+	// Orphaned is a list of instances, sorted by ID, that were decoded, but
+	// whose parent was lost. A recovering codec places these instances at
+	// the top level of the tree.
+	Orphaned []LostInstance
+}
+
+// Report generates a RecoveryReport from the current state of the model. It
+// should be called after ReadFrom, and after the model has been decoded, so
+// that chunks discarded by the codec are included.
+func (f *FormatModel) Report() *RecoveryReport {
+	report := &RecoveryReport{
+		Chunks: make([]DamagedChunk, len(f.Damaged)),
+	}
+	copy(report.Chunks, f.Damaged)
+
+	// Instances that survived, mapped to their class.
+	classes := map[int32]string{}
+	groups := map[int32]string{}
+	var parents []*ChunkParent
+	for _, chunk := range f.Chunks {
+		switch chunk := chunk.(type) {
+		case *ChunkInstance:
+			groups[chunk.TypeID] = chunk.ClassName
+			for _, id := range chunk.InstanceIDs {
+				classes[id] = chunk.ClassName
+			}
+		case *ChunkParent:
+			parents = append(parents, chunk)
+		}
+	}
+
+	// Discarded instance chunks are removed from the survivors; a codec may
+	// have discarded a chunk that was otherwise read successfully. The
+	// instances of a discarded chunk are lost, as far as the chunk could be
+	// read.
+	lostClasses := map[string]bool{}
+	lost := map[int32]string{}
+	lostParents := false
+	for _, d := range f.Damaged {
+		switch chunk := d.Chunk.(type) {
+		case *ChunkInstance:
+			if chunk.ClassName != "" {
+				lostClasses[chunk.ClassName] = true
+			}
+			if groups[chunk.TypeID] == chunk.ClassName {
+				delete(groups, chunk.TypeID)
+			}
+			for _, id := range chunk.InstanceIDs {
+				if classes[id] == chunk.ClassName {
+					delete(classes, id)
+				}
+				if _, ok := classes[id]; !ok {
+					lost[id] = chunk.ClassName
+				}
+			}
+		case *ChunkParent:
+			lostParents = true
+		case nil:
+			if d.Sig == newChunkParent().Signature() {
+				lostParents = true
+			}
+		}
+	}
+	for class := range lostClasses {
+		report.Classes = append(report.Classes, class)
+	}
+	sort.Strings(report.Classes)
+
+	lostProps := map[LostProperty]bool{}
+	for _, d := range f.Damaged {
+		if chunk, ok := d.Chunk.(*ChunkProperty); ok && chunk.PropertyName != "" {
+			lostProps[LostProperty{
+				ClassName:    groups[chunk.TypeID],
+				PropertyName: chunk.PropertyName,
+			}] = true
+		}
+	}
+	for prop := range lostProps {
+		report.Properties = append(report.Properties, prop)
+	}
+	sort.Slice(report.Properties, func(i, j int) bool {
+		a, b := report.Properties[i], report.Properties[j]
+		if a.ClassName != b.ClassName {
+			return a.ClassName < b.ClassName
+		}
+		return a.PropertyName < b.PropertyName
+	})
+
+	// Instances referred to by surviving parent chunks, but that have no
+	// surviving instance chunk, are also lost. Their class is known only if
+	// their discarded instance chunk was partially read.
+	markLost := func(id int32) {
+		if _, ok := lost[id]; !ok {
+			lost[id] = ""
+		}
+	}
+	orphaned := map[int32]bool{}
+	linked := map[int32]bool{}
+	for _, chunk := range parents {
+		for i, child := range chunk.Children {
+			if _, ok := classes[child]; !ok {
+				markLost(child)
+				continue
+			}
+			linked[child] = true
+			if i >= len(chunk.Parents) || chunk.Parents[i] == -1 {
+				continue
+			}
+			if _, ok := classes[chunk.Parents[i]]; !ok {
+				markLost(chunk.Parents[i])
+				orphaned[child] = true
+			}
+		}
+	}
+	if lostParents || len(parents) == 0 && len(f.Damaged) > 0 {
+		// Without links, every unlinked instance ends up at the top level.
+		for id := range classes {
+			if !linked[id] {
+				orphaned[id] = true
+			}
+		}
+	}
+	for id, class := range lost {
+		report.Instances = append(report.Instances, LostInstance{ID: id, ClassName: class})
+	}
+	for id := range orphaned {
+		report.Orphaned = append(report.Orphaned, LostInstance{ID: id, ClassName: classes[id]})
+	}
+	sort.Slice(report.Instances, func(i, j int) bool {
+		return report.Instances[i].ID < report.Instances[j].ID
+	})
+	sort.Slice(report.Orphaned, func(i, j int) bool {
+		return report.Orphaned[i].ID < report.Orphaned[j].ID
+	})
+
+	return report
+}
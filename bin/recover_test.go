@@ -0,0 +1,216 @@
+package bin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+func recoverTestRoot() *rbxfile.Root {
+	workspace := rbxfile.NewInstance("Workspace", nil)
+	workspace.IsService = true
+	workspace.Set("Name", rbxfile.ValueString("Workspace"))
+
+	part := rbxfile.NewInstance("Part", workspace)
+	part.Set("Name", rbxfile.ValueString("Part"))
+	part.Set("Anchored", rbxfile.ValueBool(true))
+
+	folder := rbxfile.NewInstance("Folder", part)
+	folder.Set("Name", rbxfile.ValueString("Folder"))
+
+	return &rbxfile.Root{Instances: []*rbxfile.Instance{workspace}}
+}
+
+// encodeRecoverTest encodes root into uncompressed chunks, so that payloads
+// can be located and corrupted directly.
+func encodeRecoverTest(t *testing.T, root *rbxfile.Root) []byte {
+	model, err := RobloxCodec{}.Encode(root)
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	for _, chunk := range model.Chunks {
+		if _, ok := chunk.(*ChunkEnd); !ok {
+			chunk.SetCompressed(false)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := model.WriteTo(&buf); err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	return buf.Bytes()
+}
+
+// findPropChunk returns the offset of the header of the PROP chunk with the
+// given property name and type group.
+func findPropChunk(t *testing.T, data []byte, typeID int32, name string) int {
+	for i := 0; i+rawChunkHeaderSize < len(data); i++ {
+		if string(data[i:i+4]) != "PROP" {
+			continue
+		}
+		p := data[i+rawChunkHeaderSize:]
+		if int32(binary.LittleEndian.Uint32(p[0:4])) != typeID {
+			continue
+		}
+		n := int(binary.LittleEndian.Uint32(p[4:8]))
+		if string(p[8:8+n]) == name {
+			return i
+		}
+	}
+	t.Fatalf("property chunk %q not found", name)
+	return -1
+}
+
+func typeIDOf(t *testing.T, root *rbxfile.Root, className string) int32 {
+	model, err := RobloxCodec{}.Encode(root)
+	if err != nil {
+		t.Fatalf("encode: %s", err)
+	}
+	for _, chunk := range model.Chunks {
+		if inst, ok := chunk.(*ChunkInstance); ok && inst.ClassName == className {
+			return inst.TypeID
+		}
+	}
+	t.Fatalf("class %q not found", className)
+	return -1
+}
+
+func TestRecoverDamagedProperty(t *testing.T) {
+	root := recoverTestRoot()
+	data := encodeRecoverTest(t, root)
+
+	// Replace the data type of Part.Anchored with an invalid type.
+	i := findPropChunk(t, data, typeIDOf(t, root, "Part"), "Anchored")
+	data[i+rawChunkHeaderSize+4+4+len("Anchored")] = 0xFF
+
+	if _, err := (&FormatModel{Strict: true}).ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatal("expected error in strict mode")
+	}
+
+	model := &FormatModel{Recover: true}
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if len(model.Damaged) != 1 {
+		t.Fatalf("expected 1 damaged chunk, got %d", len(model.Damaged))
+	}
+	if d := model.Damaged[0]; d.Offset != int64(i) || string(d.Sig[:]) != "PROP" {
+		t.Errorf("unexpected damaged chunk at %d (%q)", d.Offset, d.Sig)
+	}
+
+	decoded, err := RobloxCodec{}.Decode(model)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if len(decoded.Instances) != 1 || len(decoded.Instances[0].Children) != 1 {
+		t.Fatal("unexpected tree structure")
+	}
+	part := decoded.Instances[0].Children[0]
+	if part.Name() != "Part" {
+		t.Errorf("unexpected name %q", part.Name())
+	}
+	if part.Get("Anchored") != nil {
+		t.Error("expected Anchored to be lost")
+	}
+	if len(part.Children) != 1 || part.Children[0].ClassName != "Folder" {
+		t.Error("expected Folder to survive")
+	}
+
+	report := model.Report()
+	if len(report.Properties) != 1 || report.Properties[0] != (LostProperty{ClassName: "Part", PropertyName: "Anchored"}) {
+		t.Errorf("unexpected lost properties %v", report.Properties)
+	}
+	if len(report.Classes) != 0 || len(report.Instances) != 0 || len(report.Orphaned) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestRecoverMalformedHeader(t *testing.T) {
+	root := recoverTestRoot()
+	data := encodeRecoverTest(t, root)
+
+	// Corrupt the reserved field of Part.Name, forcing a resync.
+	i := findPropChunk(t, data, typeIDOf(t, root, "Part"), "Name")
+	data[i+12] = 0xFF
+
+	model := &FormatModel{Recover: true}
+	if _, err := model.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if len(model.Damaged) != 1 || model.Damaged[0].Offset != int64(i) {
+		t.Fatalf("unexpected damaged chunks %v", model.Damaged)
+	}
+	if _, ok := model.Chunks[len(model.Chunks)-1].(*ChunkEnd); !ok {
+		t.Error("expected end chunk to be recovered")
+	}
+
+	decoded, err := RobloxCodec{}.Decode(model)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	part := decoded.Instances[0].Children[0]
+	if part.Get("Name") != nil {
+		t.Error("expected Name to be lost")
+	}
+	if part.Get("Anchored") != rbxfile.ValueBool(true) {
+		t.Error("expected Anchored to survive")
+	}
+}
+
+func TestRecoverLostInstances(t *testing.T) {
+	root := recoverTestRoot()
+	data := encodeRecoverTest(t, root)
+
+	// Drop the Part class entirely; Folder should be orphaned.
+	for i := 0; i+rawChunkHeaderSize < len(data); i++ {
+		if string(data[i:i+4]) == "INST" && bytes.HasPrefix(data[i+rawChunkHeaderSize+8:], []byte("Part")) {
+			// Claim more data than the chunk contains.
+			binary.LittleEndian.PutUint32(data[i+rawChunkHeaderSize+4:], 1<<20)
+			break
+		}
+	}
+
+	root, report, err := NewSerializer(RobloxCodec{}, nil).DeserializeRecover(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if len(root.Instances) != 2 {
+		t.Fatalf("expected 2 top-level instances, got %d", len(root.Instances))
+	}
+	if root.Instances[1].ClassName != "Folder" {
+		t.Errorf("expected orphaned Folder, got %s", root.Instances[1].ClassName)
+	}
+	if len(report.Instances) != 1 || len(report.Orphaned) != 1 || report.Orphaned[0].ClassName != "Folder" {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestRecoverReportLostInstances(t *testing.T) {
+	root := recoverTestRoot()
+	data := encodeRecoverTest(t, root)
+
+	// Cut the GetService byte from the Workspace instance chunk, so that its
+	// instance IDs are read, but the chunk is discarded. Also discard the
+	// parent chunk, so that the Workspace is known only from its own chunk.
+	for i := 0; i+rawChunkHeaderSize < len(data); i++ {
+		switch {
+		case string(data[i:i+4]) == "INST" && bytes.HasPrefix(data[i+rawChunkHeaderSize+8:], []byte("Workspace")):
+			n := binary.LittleEndian.Uint32(data[i+8:])
+			binary.LittleEndian.PutUint32(data[i+8:], n-1)
+		case string(data[i:i+4]) == "PRNT":
+			binary.LittleEndian.PutUint32(data[i+rawChunkHeaderSize+1:], 1<<20)
+		}
+	}
+
+	_, report, err := NewSerializer(RobloxCodec{}, nil).DeserializeRecover(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if len(report.Instances) != 1 || report.Instances[0].ClassName != "Workspace" {
+		t.Errorf("unexpected lost instances %+v", report.Instances)
+	}
+	if len(report.Classes) != 1 || report.Classes[0] != "Workspace" {
+		t.Errorf("unexpected lost classes %+v", report.Classes)
+	}
+}
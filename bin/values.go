@@ -176,50 +176,9 @@ func interleaveFields(id Type, a []Value) (b []byte, err error) {
 	}
 
 	// list is assumed to contain the same kinds of values
-
-	// Number of bytes per field
-	nbytes := af[0].fieldLen()
-	// Number fields per value
-	nfields := len(nbytes)
-	// Number of values
-	nvalues := len(af)
-
-	// Total bytes per value
-	tbytes := 0
-	// Offset of each field slice
-	ofields := make([]int, len(nbytes)+1)
-	for i, n := range nbytes {
-		tbytes += n
-		ofields[i+1] = ofields[i] + n*nvalues
-	}
-
-	b = make([]byte, tbytes*nvalues)
-
-	// List of each field slice
-	fields := make([][]byte, nfields)
-	for i := range fields {
-		// Each field slice affects the final array
-		fields[i] = b[ofields[i]:ofields[i+1]]
-	}
-
-	for i, v := range af {
-		for f, field := range fields {
-			fb := v.fieldGet(f)
-			if len(fb) != nbytes[f] {
-				panic("length of field's bytes does not match given field length")
-			}
-			copy(field[i*nbytes[f]:], fb)
-		}
-	}
-
-	// Interleave each field slice independently
-	for i, field := range fields {
-		if err = interleave(field, nbytes[i]); err != nil {
-			return nil, err
-		}
-	}
-
-	return b, nil
+	return interleaveColumn(len(af), af[0].fieldLen(), func(i, f int) []byte {
+		return af[i].fieldGet(f)
+	})
 }
 
 // Decodes Values that implement the fielder interface.
@@ -233,55 +192,20 @@ func deinterleaveFields(id Type, b []byte) (a []Value, err error) {
 		return nil, fmt.Errorf("type identifier 0x%X is not a valid Type.", id)
 	}
 
-	// Number of bytes per field
-	nbytes := newValue().(fielder).fieldLen()
-	// Number fields per value
-	nfields := len(nbytes)
-
-	// Total bytes per value
-	tbytes := 0
-	for _, n := range nbytes {
-		tbytes += n
-	}
-
-	if len(b)%tbytes != 0 {
-		return nil, fmt.Errorf("length of array (%d) is not divisible by value byte size (%d)", len(b), tbytes)
-	}
-
-	// Number of values
-	nvalues := len(b) / tbytes
-	// Offset of each field slice
-	ofields := make([]int, len(nbytes)+1)
-	for i, n := range nbytes {
-		ofields[i+1] = ofields[i] + n*nvalues
-	}
-
-	a = make([]Value, nvalues)
-
-	// List of each field slice
-	fields := make([][]byte, nfields)
-	for i := range fields {
-		fields[i] = b[ofields[i]:ofields[i+1]]
-	}
-
-	// Deinterleave each field slice independently
-	for i, field := range fields {
-		if err = deinterleave(field, nbytes[i]); err != nil {
-			return nil, err
-		}
-	}
-
-	for i := range a {
-		v := newValue()
-		vf := v.(fielder)
-		for f, field := range fields {
-			n := nbytes[f]
-			fb := field[i*n : i*n+n]
-			vf.fieldSet(f, fb)
-		}
-		a[i] = v
+	err = deinterleaveColumn(b, newValue().(fielder).fieldLen(),
+		func(n int) {
+			a = make([]Value, n)
+			for i := range a {
+				a[i] = newValue()
+			}
+		},
+		func(i, f int, b []byte) error {
+			return a[i].(fielder).fieldSet(f, b)
+		},
+	)
+	if err != nil {
+		return nil, err
 	}
-
 	return a, nil
 }
 
@@ -81,6 +81,8 @@ func (droot Root) Declare() *rbxfile.Root {
 		}
 	}
 
+	root.FixTree()
+
 	for inst, properties := range props {
 		for _, prop := range properties {
 			inst.Properties[prop.name] = prop.typ.value(refs, prop.value)
@@ -292,6 +294,10 @@ func (property) element() {}
 //
 //     Color3uint8:
 //         3 numbers, corresponding to the R, G, and B fields.
+//
+//     ContentData:
+//         2 values, each a string or []byte, corresponding to the Hash and
+//         Data fields.
 func Property(name string, typ Type, value ...interface{}) property {
 	return property{name: name, typ: typ, value: value}
 }
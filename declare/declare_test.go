@@ -19,4 +19,5 @@ func Example() {
 			),
 		),
 	}.Declare()
+	_ = root
 }
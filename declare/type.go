@@ -50,6 +50,7 @@ const (
 	Color3uint8
 	Int64
 	SharedString
+	ContentData
 )
 
 // TypeFromString returns a Type from its string representation. Type(0) is
@@ -95,6 +96,7 @@ var typeStrings = map[Type]string{
 	Color3uint8:        "Color3uint8",
 	Int64:              "Int64",
 	SharedString:       "SharedString",
+	ContentData:        "ContentData",
 }
 
 func normUint8(v interface{}) uint8 {
@@ -381,6 +383,8 @@ func assertValue(t Type, v interface{}) (value rbxfile.Value, ok bool) {
 		value, ok = v.(rbxfile.ValueInt64)
 	case SharedString:
 		value, ok = v.(rbxfile.ValueSharedString)
+	case ContentData:
+		value, ok = v.(rbxfile.ValueContentData)
 	}
 	return
 }
@@ -719,6 +723,23 @@ func (t Type) value(refs rbxfile.References, v []interface{}) rbxfile.Value {
 		case []byte:
 			return rbxfile.ValueSharedString(v)
 		}
+	case ContentData:
+		if len(v) == 2 {
+			var value rbxfile.ValueContentData
+			switch v := v[0].(type) {
+			case string:
+				value.Hash = v
+			case []byte:
+				value.Hash = string(v)
+			}
+			switch v := v[1].(type) {
+			case string:
+				value.Data = []byte(v)
+			case []byte:
+				value.Data = v
+			}
+			return value
+		}
 	}
 
 zero:
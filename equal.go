@@ -0,0 +1,223 @@
+package rbxfile
+
+import (
+	"math"
+	"reflect"
+	"sort"
+)
+
+// EqualOptions configures the comparison made by Equal and EqualRoot.
+type EqualOptions struct {
+	// Epsilon is the largest difference allowed between two floating-point
+	// components of a value, such as the X of a Vector3. Values encoded by
+	// different formats may differ by a small amount.
+	Epsilon float64
+
+	// IgnoreReferences causes the Reference field of instances to be ignored.
+	// Reference properties are always compared by the position of the
+	// referent within the tree, rather than by the reference string.
+	IgnoreReferences bool
+
+	// Property, if not nil, is called with the instance and name of each
+	// property. Only properties for which Property returns true are
+	// compared.
+	Property func(inst *Instance, name string) bool
+}
+
+// Equal returns whether two instance trees are equal. Instances are equal if
+// their ClassName, IsService, Reference, and properties are equal, and their
+// children are equal, in order. Properties are compared by value, as
+// configured by opts.
+//
+// If the trees are not equal, then the path of the first differing instance
+// is also returned, in the form accepted by ParsePath, relative to the parent
+// of a. If the difference is in a property, then the path is followed by a
+// colon and the name of the property.
+func Equal(a, b *Instance, opts EqualOptions) (equal bool, path string) {
+	if a == nil || b == nil {
+		return a == b, ""
+	}
+	c := newComparer(opts)
+	c.index([]*Instance{a}, []*Instance{b})
+	return c.compareList([]*Instance{a}, []*Instance{b}, nil)
+}
+
+// EqualRoot returns whether the instances of two roots are equal, as with
+// Equal. The path of the first difference is relative to the root. Metadata
+// and Extra are not compared.
+func EqualRoot(a, b *Root, opts EqualOptions) (equal bool, path string) {
+	if a == nil || b == nil {
+		return a == b, ""
+	}
+	c := newComparer(opts)
+	c.index(a.Instances, b.Instances)
+	return c.compareList(a.Instances, b.Instances, nil)
+}
+
+// comparer holds the state of a comparison between two trees.
+type comparer struct {
+	opts EqualOptions
+	// Maps each instance in each tree to its position in the tree.
+	posA, posB map[*Instance]int
+}
+
+func newComparer(opts EqualOptions) *comparer {
+	return &comparer{
+		opts: opts,
+		posA: map[*Instance]int{},
+		posB: map[*Instance]int{},
+	}
+}
+
+// index records the position of each instance in the trees of a and b.
+func (c *comparer) index(a, b []*Instance) {
+	indexTree := func(list []*Instance, pos map[*Instance]int) {
+		for _, inst := range list {
+			inst.Walk(func(desc *Instance, depth int) WalkAction {
+				pos[desc] = len(pos)
+				return WalkContinue
+			}, nil)
+		}
+	}
+	indexTree(a, c.posA)
+	indexTree(b, c.posB)
+}
+
+// compareList compares two lists of sibling instances. path is the path of
+// the parent.
+func (c *comparer) compareList(a, b []*Instance, path []PathElement) (bool, string) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		list, inst := a, (*Instance)(nil)
+		if i < len(a) {
+			inst = a[i]
+		} else {
+			list, inst = b, b[i]
+		}
+		elem := PathElement{Name: inst.Name(), Index: siblingIndex(list[:i], inst.Name())}
+		elements := append(path[:len(path):len(path)], elem)
+		if i >= len(a) || i >= len(b) {
+			return false, FormatPath(elements)
+		}
+		if ok, p := c.compare(a[i], b[i], elements); !ok {
+			return false, p
+		}
+	}
+	return true, ""
+}
+
+// siblingIndex returns the index of a path element that selects an instance
+// named name, which follows the instances in prev.
+func siblingIndex(prev []*Instance, name string) int {
+	n := 1
+	for _, inst := range prev {
+		if inst.Name() == name {
+			n++
+		}
+	}
+	return n
+}
+
+// compare compares two instances, located at path.
+func (c *comparer) compare(a, b *Instance, path []PathElement) (bool, string) {
+	if a.ClassName != b.ClassName ||
+		a.IsService != b.IsService ||
+		!c.opts.IgnoreReferences && a.Reference != b.Reference {
+		return false, FormatPath(path)
+	}
+
+	names := c.propertyNames(a, nil)
+	names = c.propertyNames(b, names)
+	sort.Strings(names)
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		if !c.equalValue(a.Get(name), b.Get(name)) {
+			return false, FormatPath(path) + ":" + name
+		}
+	}
+
+	return c.compareList(a.Children, b.Children, path)
+}
+
+// propertyNames appends to names the names of the properties of inst that
+// pass the property filter.
+func (c *comparer) propertyNames(inst *Instance, names []string) []string {
+	inst.Range(func(name string, value Value) bool {
+		if c.opts.Property == nil || c.opts.Property(inst, name) {
+			names = append(names, name)
+		}
+		return true
+	})
+	return names
+}
+
+// equalValue returns whether two property values are equal.
+func (c *comparer) equalValue(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if a, ok := a.(ValueReference); ok {
+		return c.equalReference(a.Instance, b.(ValueReference).Instance)
+	}
+	return c.equalReflect(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// equalReference returns whether two referents are equal. Referents within
+// the compared trees are equal if they have the same position. Otherwise, they
+// must be the same instance.
+func (c *comparer) equalReference(a, b *Instance) bool {
+	pa, okA := c.posA[a]
+	pb, okB := c.posB[b]
+	if okA || okB {
+		return okA && okB && pa == pb
+	}
+	return a == b
+}
+
+// equalReflect compares the underlying data of two values of the same type.
+func (c *comparer) equalReflect(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return c.equalFloat(a.Float(), b.Float())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !c.equalReflect(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array, reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !c.equalReflect(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() == b.Uint()
+	case reflect.String:
+		return a.String() == b.String()
+	default:
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+}
+
+// equalFloat returns whether two floats are within the configured epsilon.
+// NaNs are equal to each other.
+func (c *comparer) equalFloat(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return a == b || math.Abs(a-b) <= c.opts.Epsilon
+}
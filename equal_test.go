@@ -0,0 +1,93 @@
+package rbxfile
+
+import (
+	"testing"
+)
+
+func equalTestTree() *Instance {
+	model := refInst("Model", "RBX1", nil)
+	a := refInst("Part", "RBX2", model)
+	b := refInst("Part", "RBX3", model)
+	a.Set("Size", ValueVector3{X: 1, Y: 2, Z: 3})
+	a.Set("Data", ValueSharedString("data"))
+	b.Set("Target", ValueReference{Instance: a})
+	b.Set("Keypoints", ValueNumberSequence{{Time: 0, Value: 1}, {Time: 1, Value: 0.5}})
+	return model
+}
+
+func TestEqual(t *testing.T) {
+	a, b := equalTestTree(), equalTestTree()
+	if ok, path := Equal(a, b, EqualOptions{}); !ok {
+		t.Errorf("expected equal trees, differ at %q", path)
+	}
+	// A clone has new references.
+	if ok, path := Equal(a, a.Clone(), EqualOptions{IgnoreReferences: true}); !ok {
+		t.Errorf("expected clone to be equal, differ at %q", path)
+	}
+	b.Children[1].Reference = "RBX4"
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part[2]" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+	if ok, path := Equal(a, b, EqualOptions{IgnoreReferences: true}); !ok {
+		t.Errorf("expected references to be ignored, differ at %q", path)
+	}
+	if ok, _ := Equal(nil, nil, EqualOptions{}); !ok {
+		t.Error("expected nil trees to be equal")
+	}
+	if ok, _ := Equal(a, nil, EqualOptions{}); ok {
+		t.Error("expected nil tree to differ")
+	}
+
+	b = equalTestTree()
+	b.Children[0].Set("Size", ValueVector3{X: 1, Y: 2.000001, Z: 3})
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part:Size" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+	if ok, path := Equal(a, b, EqualOptions{Epsilon: 1e-5}); !ok {
+		t.Errorf("expected equal within epsilon, differ at %q", path)
+	}
+
+	b = equalTestTree()
+	b.Children[1].Set("Target", ValueReference{Instance: b.Children[1]})
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part[2]:Target" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+	ignoreTarget := func(inst *Instance, name string) bool { return name != "Target" }
+	if ok, path := Equal(a, b, EqualOptions{Property: ignoreTarget}); !ok {
+		t.Errorf("expected filtered property to be ignored, differ at %q", path)
+	}
+
+	b = equalTestTree()
+	b.Children[1].Set("Extra", ValueBool(true))
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part[2]:Extra" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+
+	b = equalTestTree()
+	refInst("Child", "RBX4", b.Children[0])
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part.Child" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+
+	b = equalTestTree()
+	b.Children[1].ClassName = "Decal"
+	if ok, path := Equal(a, b, EqualOptions{}); ok || path != "Model.Part[2]" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+}
+
+func TestEqualRoot(t *testing.T) {
+	a := &Root{Instances: []*Instance{equalTestTree(), namedInst("Folder", nil)}}
+	b := &Root{Instances: []*Instance{equalTestTree(), namedInst("Folder", nil)}}
+	b.Instances[1].Reference = a.Instances[1].Reference
+	if ok, path := EqualRoot(a, b, EqualOptions{}); !ok {
+		t.Errorf("expected equal roots, differ at %q", path)
+	}
+	if ok, path := EqualRoot(a, a.Copy(), EqualOptions{IgnoreReferences: true}); !ok {
+		t.Errorf("expected copy to be equal, differ at %q", path)
+	}
+	b.Instances = b.Instances[:1]
+	if ok, path := EqualRoot(a, b, EqualOptions{}); ok || path != "Folder" {
+		t.Errorf("unexpected result %t, %q", ok, path)
+	}
+}
@@ -23,6 +23,7 @@ package rbxfile
 
 import (
 	"errors"
+	"fmt"
 )
 
 ////////////////////////////////////////////////////////////////
@@ -30,11 +31,29 @@ import (
 // Root represents the root of an instance tree. Root is not itself an
 // instance, but a container for multiple root instances.
 type Root struct {
-	// Instances contains root instances contained in the tree.
+	// Instances contains root instances contained in the tree. The root owns
+	// the instances added with AddInstance, so that they are removed from
+	// the root when their parent is set. Instances that are added to the list
+	// directly are owned after calling FixTree.
 	Instances []*Instance
 
 	// Metadata contains metadata about the tree.
 	Metadata map[string]string
+
+	// ReferenceGenerator is used to generate references for instances in the
+	// tree, such as when the tree is copied or encoded. If nil, then
+	// CryptoReferences is used.
+	ReferenceGenerator ReferenceGenerator
+
+	// Extra holds format-specific data that has no other representation in
+	// the tree, such as unrecognized content that was preserved while
+	// decoding. A format that supports such data writes it back when
+	// encoding. The type of the value depends on the format.
+	Extra interface{}
+
+	// refs indexes the instances in the tree by reference. It is built when
+	// first needed, and maintained as instances are added and removed.
+	refs References
 }
 
 // NewRoot returns a new initialized Root.
@@ -51,16 +70,22 @@ func NewRoot() *Root {
 // corresponding copy of the original referent. Copied references that point
 // to an instance which isn't being copied will still point to the same
 // instance.
+//
+// New references are generated with root.ReferenceGenerator, which is also
+// set on the copy.
 func (root *Root) Copy() *Root {
 	clone := &Root{
-		Instances: make([]*Instance, len(root.Instances)),
+		Instances:          make([]*Instance, len(root.Instances)),
+		ReferenceGenerator: root.ReferenceGenerator,
+		Extra:              root.Extra,
 	}
 
 	refs := make(References)
 	crefs := make(References)
 	propRefs := make([]PropRef, 0, 8)
 	for i, inst := range root.Instances {
-		clone.Instances[i] = inst.clone(refs, crefs, &propRefs)
+		clone.Instances[i] = inst.clone(refs, crefs, &propRefs, root.ReferenceGenerator)
+		clone.Instances[i].root = clone
 	}
 	for _, propRef := range propRefs {
 		if !crefs.Resolve(propRef) {
@@ -72,6 +97,116 @@ func (root *Root) Copy() *Root {
 	return clone
 }
 
+// AddInstance appends an instance to the root's list of instances, and makes
+// the root its owner. If the instance has a parent or another owner, it is
+// first removed. An error is returned if the instance is a service, and the
+// root already has a service of the same class, or if a reference in the
+// instance's tree collides with a reference in the root's tree.
+func (root *Root) AddInstance(inst *Instance) error {
+	if inst == nil {
+		return errors.New("instance is nil")
+	}
+	if inst.root == root && inst.parent == nil {
+		return nil
+	}
+	if inst.IsService {
+		if service := root.findService(inst.ClassName); service != nil && service != inst {
+			return fmt.Errorf("service %s already exists", inst.ClassName)
+		}
+	}
+	if err := root.checkReferences(inst); err != nil {
+		return err
+	}
+	inst.detach()
+	root.Instances = append(root.Instances, inst)
+	inst.root = root
+	root.indexTree(inst)
+	return nil
+}
+
+// RemoveInstance removes an instance from the root's list of instances, so
+// that the root no longer owns it. Returns the removed instance, or nil if the
+// instance was not found.
+func (root *Root) RemoveInstance(inst *Instance) *Instance {
+	for i, r := range root.Instances {
+		if r == inst {
+			copy(root.Instances[i:], root.Instances[i+1:])
+			root.Instances[len(root.Instances)-1] = nil
+			root.Instances = root.Instances[:len(root.Instances)-1]
+			if inst.root == root {
+				inst.root = nil
+			}
+			root.unindexTree(inst)
+			return inst
+		}
+	}
+	return nil
+}
+
+// findService returns the first root instance that is a service of the given
+// class.
+func (root *Root) findService(className string) *Instance {
+	for _, inst := range root.Instances {
+		if inst.IsService && inst.ClassName == className {
+			return inst
+		}
+	}
+	return nil
+}
+
+// GetService returns the service of the given class. If the root does not
+// have the service, then a root instance of the class is marked as the
+// service. If there is no such instance, then the service is created, named
+// after its class, and added to the root. The reference of a created service
+// is generated by the ReferenceGenerator of the root, if it is set. If the
+// reference collides with an instance in the tree of the root, then the
+// service is given no reference instead.
+func (root *Root) GetService(className string) *Instance {
+	if service := root.findService(className); service != nil {
+		return service
+	}
+	for _, inst := range root.Instances {
+		if inst.ClassName == className {
+			inst.IsService = true
+			return inst
+		}
+	}
+	service := NewInstance(className, nil)
+	service.IsService = true
+	service.SetName(className)
+	if root.ReferenceGenerator != nil {
+		service.Reference = root.ReferenceGenerator.GenerateReference()
+	}
+	if err := root.AddInstance(service); err != nil {
+		// The root has no service of the class, so the error can only be a
+		// collision of the reference. Without a reference, adding cannot
+		// fail.
+		service.Reference = ""
+		root.AddInstance(service)
+	}
+	return service
+}
+
+// FixTree ensures that the root owns each of its instances, and that they
+// have no parent, then calls Instance.FixTree on each instance. The reference
+// index of the root is also rebuilt.
+func (root *Root) FixTree() {
+	root.refs = nil
+	for _, inst := range root.Instances {
+		if inst.parent != nil {
+			inst.parent.RemoveChild(inst)
+		}
+		inst.root = root
+		inst.FixTree()
+	}
+}
+
+// IsAncestorOf returns whether an instance is in the tree of the root. This
+// is the case when the instance or one of its ancestors is owned by the root.
+func (root *Root) IsAncestorOf(descendant *Instance) bool {
+	return descendant != nil && descendant.Root() == root
+}
+
 // Instance represents a single Roblox instance.
 type Instance struct {
 	// ClassName indicates the instance's type.
@@ -87,6 +222,11 @@ type Instance struct {
 
 	// Properties is a map of properties of the instance. It maps the name of
 	// the property to its current value.
+	//
+	// If the instance has a PropertyLoader, then Properties does not contain
+	// the properties that have yet to be loaded. Get, Set and Range load
+	// properties as needed, while LoadProperties loads every property, after
+	// which Properties may be accessed directly.
 	Properties map[string]Value
 
 	// Children contains instances that are the children of the current
@@ -94,8 +234,24 @@ type Instance struct {
 	// afterwards to ensure the correctness of the tree.
 	Children []*Instance
 
+	// Extra holds format-specific data that has no other representation in
+	// the instance, in the same manner as Root.Extra. It is copied by
+	// reference when the instance is cloned or imported.
+	Extra interface{}
+
 	// The parent of the instance. Can be nil.
 	parent *Instance
+
+	// The root that owns the instance, if the instance is one of the root's
+	// instances.
+	root *Root
+
+	// loader provides properties that have yet to be loaded, where
+	// loaderIndex is the index of the instance within the loader. loaded
+	// indicates which properties of the loader have been loaded.
+	loader      PropertyLoader
+	loaderIndex int
+	loaded      []bool
 }
 
 // NewInstance creates a new Instance of a given class, and an optional
@@ -107,8 +263,7 @@ func NewInstance(className string, parent *Instance) *Instance {
 		Properties: make(map[string]Value, 0),
 	}
 	if parent != nil {
-		parent.Children = append(parent.Children, inst)
-		inst.parent = parent
+		parent.addChild(inst)
 	}
 	return inst
 }
@@ -125,22 +280,51 @@ func assertLoop(child, parent *Instance) error {
 	return nil
 }
 
+// assertInsert returns an error if child cannot be added to parent, either
+// because of a circular reference, or because the references of the child's
+// tree collide with the tree of parent's root.
+func assertInsert(child, parent *Instance) error {
+	if err := assertLoop(child, parent); err != nil {
+		return err
+	}
+	if parent != nil {
+		if root := parent.Root(); root != nil {
+			return root.checkReferences(child)
+		}
+	}
+	return nil
+}
+
+// detach removes the instance from its parent, or from the root that owns it.
+func (inst *Instance) detach() {
+	if inst.parent != nil {
+		inst.parent.RemoveChild(inst)
+	}
+	if inst.root != nil {
+		inst.root.RemoveInstance(inst)
+	}
+}
+
 // addChild appends a child to the instance, and sets its parent. If the child
-// is already the child of another instance, it is first removed.
+// is already the child of another instance, or is owned by a root, it is first
+// removed.
 func (inst *Instance) addChild(child *Instance) {
-	if child.parent != nil {
-		child.parent.RemoveChild(child)
-	}
+	child.detach()
 	inst.Children = append(inst.Children, child)
 	child.parent = inst
+	if root := inst.Root(); root != nil {
+		root.indexTree(child)
+	}
 }
 
 // AddChild appends a child instance to the instance's list of children. If
 // the child has a parent, it is first removed. The parent of the child is set
 // to the instance. An error is returned if the instance is a descendant of
-// the child, or if the child is the instance itself.
+// the child, or if the child is the instance itself. If the instance is in the
+// tree of a root, an error is also returned if a reference in the child's tree
+// collides with a reference in the root's tree.
 func (inst *Instance) AddChild(child *Instance) error {
-	if err := assertLoop(child, inst); err != nil {
+	if err := assertInsert(child, inst); err != nil {
 		return err
 	}
 	inst.addChild(child)
@@ -152,9 +336,9 @@ func (inst *Instance) AddChild(child *Instance) error {
 // parent of the child is set to the instance. If the index is outside the
 // bounds of the list, then it is constrained. An error is returned if the
 // instance is a descendant of the child, or if the child is the instance
-// itself.
+// itself, or if the references of the child collide, as with AddChild.
 func (inst *Instance) AddChildAt(index int, child *Instance) error {
-	if err := assertLoop(child, inst); err != nil {
+	if err := assertInsert(child, inst); err != nil {
 		return err
 	}
 	if index < 0 {
@@ -163,13 +347,14 @@ func (inst *Instance) AddChildAt(index int, child *Instance) error {
 		inst.addChild(child)
 		return nil
 	}
-	if child.parent != nil {
-		child.parent.RemoveChild(child)
-	}
+	child.detach()
 	inst.Children = append(inst.Children, nil)
 	copy(inst.Children[index+1:], inst.Children[index:])
 	inst.Children[index] = child
 	child.parent = inst
+	if root := inst.Root(); root != nil {
+		root.indexTree(child)
+	}
 	return nil
 }
 
@@ -178,6 +363,9 @@ func (inst *Instance) AddChildAt(index int, child *Instance) error {
 func (inst *Instance) removeChildAt(index int) (child *Instance) {
 	child = inst.Children[index]
 	child.parent = nil
+	if root := inst.Root(); root != nil {
+		root.unindexTree(child)
+	}
 	copy(inst.Children[index:], inst.Children[index+1:])
 	inst.Children[len(inst.Children)-1] = nil
 	inst.Children = inst.Children[:len(inst.Children)-1]
@@ -209,7 +397,11 @@ func (inst *Instance) RemoveChildAt(index int) *Instance {
 // RemoveAll remove every child from the instance. The parent of each child is
 // set to nil.
 func (inst *Instance) RemoveAll() {
+	root := inst.Root()
 	for i, child := range inst.Children {
+		if root != nil {
+			root.unindexTree(child)
+		}
 		child.parent = nil
 		inst.Children[i] = nil
 	}
@@ -222,22 +414,32 @@ func (inst *Instance) Parent() *Instance {
 	return inst.parent
 }
 
+// Root returns the root that owns the instance or its top-level ancestor.
+// Returns nil if the tree is not owned by a root.
+func (inst *Instance) Root() *Root {
+	object := inst
+	for object.parent != nil {
+		object = object.parent
+	}
+	return object.root
+}
+
 // SetParent sets the parent of the instance, removing itself from the
 // children of the old parent, and adding itself as a child of the new parent.
-// The parent can be set to nil. An error is returned if the parent is a
-// descendant of the instance, or if the parent is the instance itself. If the
-// new parent is the same as the old parent, then the position of the instance
-// in the parent's children is unchanged.
+// If the instance is owned by a root, then it is removed from the root. The
+// parent can be set to nil. An error is returned if the parent is a
+// descendant of the instance, or if the parent is the instance itself, or if
+// the references of the instance collide, as with AddChild. If the new parent
+// is the same as the old parent, then the position of the instance in the
+// parent's children is unchanged.
 func (inst *Instance) SetParent(parent *Instance) error {
-	if inst.parent == parent {
+	if inst.parent == parent && inst.root == nil {
 		return nil
 	}
-	if err := assertLoop(inst, parent); err != nil {
+	if err := assertInsert(inst, parent); err != nil {
 		return err
 	}
-	if inst.parent != nil {
-		inst.parent.RemoveChild(inst)
-	}
+	inst.detach()
 	if parent != nil {
 		parent.addChild(inst)
 	}
@@ -260,12 +462,15 @@ func (inst *Instance) FixTree() {
 	}
 }
 
-// clone returns a deep copy of the instance while managing references.
-func (inst *Instance) clone(refs, crefs References, propRefs *[]PropRef) *Instance {
+// clone returns a deep copy of the instance while managing references. New
+// references are generated with gen.
+func (inst *Instance) clone(refs, crefs References, propRefs *[]PropRef, gen ReferenceGenerator) *Instance {
+	inst.LoadProperties()
 	clone := &Instance{
 		ClassName:  inst.ClassName,
-		Reference:  refs.Get(inst),
+		Reference:  refs.GetWith(inst, gen),
 		IsService:  inst.IsService,
+		Extra:      inst.Extra,
 		Children:   make([]*Instance, len(inst.Children)),
 		Properties: make(map[string]Value, len(inst.Properties)),
 	}
@@ -275,14 +480,14 @@ func (inst *Instance) clone(refs, crefs References, propRefs *[]PropRef) *Instan
 			*propRefs = append(*propRefs, PropRef{
 				Instance:  clone,
 				Property:  name,
-				Reference: refs.Get(value.Instance),
+				Reference: refs.GetWith(value.Instance, gen),
 			})
 			continue
 		}
 		clone.Properties[name] = value.Copy()
 	}
 	for i, child := range inst.Children {
-		c := child.clone(refs, crefs, propRefs)
+		c := child.clone(refs, crefs, propRefs, gen)
 		clone.Children[i] = c
 		c.parent = clone
 	}
@@ -297,21 +502,64 @@ func (inst *Instance) clone(refs, crefs References, propRefs *[]PropRef) *Instan
 // corresponding copy of the original referent. Copied references that point
 // to an instance which isn't being copied will still point to the same
 // instance.
+//
+// Each copy is given a new reference, so that the clone can be added to the
+// same tree as the original. References are generated with the
+// ReferenceGenerator of the root that owns the instance, if there is one.
 func (inst *Instance) Clone() *Instance {
-	refs := make(References)
-	crefs := make(References)
-	propRefs := make([]PropRef, 0, 8)
-	clone := inst.clone(refs, crefs, &propRefs)
+	var gen ReferenceGenerator
+	if root := inst.Root(); root != nil {
+		gen = root.ReferenceGenerator
+	}
+	return inst.CloneWith(gen)
+}
+
+// CloneWith is like Clone, but generates new references with gen. If gen is
+// nil, then CryptoReferences is used.
+func (inst *Instance) CloneWith(gen ReferenceGenerator) *Instance {
+	copies := map[*Instance]*Instance{}
+	var propRefs []PropRef
+	clone := inst.cloneFresh(gen, copies, &propRefs)
 	for _, propRef := range propRefs {
-		if !crefs.Resolve(propRef) {
-			// Refers to an instance outside the tree, try getting the
-			// original referent.
-			refs.Resolve(propRef)
+		value := propRef.Instance.Properties[propRef.Property].(ValueReference)
+		if c, ok := copies[value.Instance]; ok {
+			propRef.Instance.Properties[propRef.Property] = ValueReference{Instance: c}
 		}
 	}
 	return clone
 }
 
+// cloneFresh returns a deep copy of the instance, where each copy is given a
+// reference generated by gen. Each copy is recorded in copies, and the
+// reference properties of copies are added to propRefs, to be resolved once
+// the tree is copied.
+func (inst *Instance) cloneFresh(gen ReferenceGenerator, copies map[*Instance]*Instance, propRefs *[]PropRef) *Instance {
+	inst.LoadProperties()
+	clone := &Instance{
+		ClassName:  inst.ClassName,
+		Reference:  generateReference(gen),
+		IsService:  inst.IsService,
+		Extra:      inst.Extra,
+		Children:   make([]*Instance, len(inst.Children)),
+		Properties: make(map[string]Value, len(inst.Properties)),
+	}
+	copies[inst] = clone
+	for name, value := range inst.Properties {
+		if value, ok := value.(ValueReference); ok {
+			*propRefs = append(*propRefs, PropRef{Instance: clone, Property: name})
+			clone.Properties[name] = value
+			continue
+		}
+		clone.Properties[name] = value.Copy()
+	}
+	for i, child := range inst.Children {
+		c := child.cloneFresh(gen, copies, propRefs)
+		clone.Children[i] = c
+		c.parent = clone
+	}
+	return clone
+}
+
 // FindFirstChild returns the first found child whose Name property matches
 // the given name. Returns nil if no child was found. If recursive is true,
 // then FindFirstChild will be called on descendants as well.
@@ -384,8 +632,8 @@ func (inst *Instance) IsDescendantOf(ancestor *Instance) bool {
 // Name returns the Name property of the instance, or an empty string if it is
 // invalid or not defined.
 func (inst *Instance) Name() string {
-	iname, ok := inst.Properties["Name"]
-	if !ok {
+	iname := inst.Get("Name")
+	if iname == nil {
 		return ""
 	}
 
@@ -396,8 +644,8 @@ func (inst *Instance) Name() string {
 // String implements the fmt.Stringer interface by returning the Name of the
 // instance, or the ClassName if Name isn't defined.
 func (inst *Instance) String() string {
-	iname, ok := inst.Properties["Name"]
-	if !ok {
+	iname := inst.Get("Name")
+	if iname == nil {
 		return inst.ClassName
 	}
 
@@ -411,21 +659,114 @@ func (inst *Instance) String() string {
 
 // SetName sets the Name property of the instance.
 func (inst *Instance) SetName(name string) {
-	inst.Properties["Name"] = ValueString(name)
+	inst.Set("Name", ValueString(name))
 }
 
 // Get returns the value of a property in the instance. The value will be nil
 // if the property is not defined.
 func (inst *Instance) Get(property string) (value Value) {
+	inst.loadProperty(property, true)
 	return inst.Properties[property]
 }
 
 // Set sets the value of a property in the instance. If value is nil, then the
 // value will be deleted from the Properties map.
 func (inst *Instance) Set(property string, value Value) {
+	inst.loadProperty(property, false)
 	if value == nil {
 		delete(inst.Properties, property)
 	} else {
 		inst.Properties[property] = value
 	}
 }
+
+// Range calls fn for each property of the instance, in no particular order,
+// loading every property first. Iteration stops if fn returns false.
+func (inst *Instance) Range(fn func(property string, value Value) bool) {
+	inst.LoadProperties()
+	for name, value := range inst.Properties {
+		if !fn(name, value) {
+			break
+		}
+	}
+}
+
+// PropertyLoader provides the values of properties that are decoded on
+// demand. A loader provides the same set of properties for a group of
+// instances, where each instance is identified by an index.
+type PropertyLoader interface {
+	// PropertyNames returns the names of the properties provided by the
+	// loader. The result must not be modified.
+	PropertyNames() []string
+
+	// LoadProperty returns the value of the property named by
+	// PropertyNames()[p] for the instance at index i. Returns nil if the
+	// value could not be loaded.
+	LoadProperty(i, p int) Value
+}
+
+// SetPropertyLoader sets loader as the source of the properties of the
+// instance, where i is the index of the instance within the loader. A
+// property provided by the loader is loaded when it is first accessed, unless
+// it is already present in Properties. If loader is nil, then any properties
+// that have yet to be loaded are discarded.
+//
+// Because loading modifies Properties, an instance with a loader is not safe
+// for concurrent use, even when only reading.
+func (inst *Instance) SetPropertyLoader(loader PropertyLoader, i int) {
+	inst.loader = loader
+	inst.loaderIndex = i
+	inst.loaded = nil
+	if loader != nil {
+		inst.loaded = make([]bool, len(loader.PropertyNames()))
+	}
+}
+
+// LoadProperties loads every property that has yet to be loaded from the
+// PropertyLoader of the instance, after which the instance no longer has a
+// loader.
+func (inst *Instance) LoadProperties() {
+	if inst.loader == nil {
+		return
+	}
+	for p, name := range inst.loader.PropertyNames() {
+		inst.load(p, name, true)
+	}
+	inst.loader = nil
+	inst.loaded = nil
+}
+
+// loadProperty loads the given property from the PropertyLoader, if it has
+// yet to be loaded. If decode is false, the property is marked as loaded
+// without retrieving its value.
+func (inst *Instance) loadProperty(property string, decode bool) {
+	if inst.loader == nil {
+		return
+	}
+	names := inst.loader.PropertyNames()
+	for p := len(names) - 1; p >= 0; p-- {
+		if names[p] == property {
+			inst.load(p, property, decode)
+			return
+		}
+	}
+}
+
+func (inst *Instance) load(p int, property string, decode bool) {
+	if inst.loaded[p] {
+		return
+	}
+	inst.loaded[p] = true
+	if !decode {
+		return
+	}
+	if _, ok := inst.Properties[property]; ok {
+		return
+	}
+	if value := inst.loader.LoadProperty(inst.loaderIndex, p); value != nil {
+		if inst.Properties == nil {
+			inst.Properties = make(map[string]Value)
+		}
+		inst.Properties[property] = value
+	}
+}
@@ -33,7 +33,7 @@ func TestRootCopy(t *testing.T) {
 	}
 	for i := 0; i < len(r.Instances); i++ {
 		if a, b := r.Instances[i].ClassName, rc.Instances[i].ClassName; a != b {
-			t.Errorf("mismatched instance %d (expected %s, got %s)", a, b)
+			t.Errorf("mismatched instance %d (expected %s, got %s)", i, a, b)
 		}
 		if r.Instances[i] == rc.Instances[i] {
 			t.Errorf("instance %d in copy equals instance in root", i)
@@ -257,7 +257,7 @@ func TestInstance_AddChild(t *testing.T) {
 		t.Error("unexpected parent")
 	}
 	if n := len(parent.Children); n != 2 {
-		t.Error("unexpected length of children (expected 2, got %d):", n)
+		t.Errorf("unexpected length of children (expected 2, got %d)", n)
 	}
 	if parent.Children[0] != sibling {
 		t.Error("unexpected sibling")
@@ -303,7 +303,7 @@ func TestInstance_AddChildAt(t *testing.T) {
 		t.Error("unexpected parent")
 	}
 	if n := len(parent.Children); n != 2 {
-		t.Error("unexpected length of children (expected 2, got %d):", n)
+		t.Errorf("unexpected length of children (expected 2, got %d)", n)
 	}
 	if parent.Children[0] != sibling {
 		t.Error("unexpected sibling")
@@ -328,11 +328,11 @@ func TestInstance_AddChildAt(t *testing.T) {
 	child2 := NewInstance("Child2", nil)
 	assertOrder := func(children ...*Instance) {
 		if i, j := len(children), len(parent.Children); i != j {
-			t.Error("unexpected number of children (expected %d, got %d)", i, j)
+			t.Errorf("unexpected number of children (expected %d, got %d)", i, j)
 		}
 		for i := 0; i < len(children); i++ {
 			if parent.Children[i] != children[i] {
-				t.Error("unexpected child %d (expected %s, got %s)", children[i].ClassName, parent.Children[i].ClassName)
+				t.Errorf("unexpected child %d (expected %s, got %s)", i, children[i].ClassName, parent.Children[i].ClassName)
 			}
 		}
 	}
@@ -509,11 +509,11 @@ func TestInstance_RemoveAll(t *testing.T) {
 
 	parent.RemoveAll()
 	if i := len(parent.Children); i != 0 {
-		t.Error("expected Children length of 0 (got %d)", i)
+		t.Errorf("expected Children length of 0 (got %d)", i)
 	}
 	for i, child := range children {
 		if child.Parent() != nil {
-			t.Error("expected nil parent on child %d", i)
+			t.Errorf("expected nil parent on child %d", i)
 		}
 	}
 }
@@ -717,3 +717,204 @@ func TestInstance_GetSet(t *testing.T) {
 		t.Error("unexpected value returned from Get")
 	}
 }
+
+type testLoader struct {
+	names []string
+	loads int
+}
+
+func (l *testLoader) PropertyNames() []string {
+	return l.names
+}
+
+func (l *testLoader) LoadProperty(i, p int) Value {
+	l.loads++
+	return ValueString(l.names[p] + strconv.Itoa(i))
+}
+
+func TestInstance_PropertyLoader(t *testing.T) {
+	loader := &testLoader{names: []string{"Name", "A", "B"}}
+	inst := NewInstance("Instance", nil)
+	inst.SetPropertyLoader(loader, 1)
+	str := func(v Value) string {
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+
+	if len(inst.Properties) != 0 {
+		t.Error("expected no properties before loading")
+	}
+	if v := inst.Get("A"); str(v) != "A1" {
+		t.Errorf("unexpected value %v", v)
+	}
+	inst.Get("A")
+	if loader.loads != 1 {
+		t.Errorf("expected 1 load, got %d", loader.loads)
+	}
+	if inst.Name() != "Name1" {
+		t.Errorf("unexpected name %q", inst.Name())
+	}
+
+	// Setting a property does not load it, and deleting it does not cause it
+	// to be loaded again.
+	inst.Set("B", nil)
+	if str(inst.Get("B")) != "" || loader.loads != 2 {
+		t.Error("expected deleted property to remain absent")
+	}
+
+	inst.SetPropertyLoader(loader, 2)
+	inst.Set("A", ValueString("set"))
+	n := 0
+	inst.Range(func(property string, value Value) bool {
+		n++
+		return true
+	})
+	if n != 3 || str(inst.Get("A")) != "set" || str(inst.Get("B")) != "B2" {
+		t.Errorf("unexpected properties %v", inst.Properties)
+	}
+
+	inst.SetPropertyLoader(loader, 3)
+	clone := inst.Clone()
+	if len(clone.Properties) != 3 || str(clone.Get("Name")) != "Name1" {
+		t.Errorf("unexpected cloned properties %v", clone.Properties)
+	}
+}
+
+func TestRoot_AddInstance(t *testing.T) {
+	root := &Root{}
+	parent := namedInst("Parent", nil)
+	inst := namedInst("Instance", parent)
+	child := namedInst("Child", inst)
+
+	if err := root.AddInstance(nil); err == nil {
+		t.Error("no error on adding nil instance")
+	}
+	if err := root.AddInstance(inst); err != nil {
+		t.Error("failed add instance:", err)
+	}
+	if inst.Parent() != nil {
+		t.Error("expected nil parent")
+	}
+	if len(parent.Children) != 0 {
+		t.Error("instance not removed from parent")
+	}
+	if len(root.Instances) != 1 || root.Instances[0] != inst {
+		t.Error("instance not added to root")
+	}
+	if inst.Root() != root || child.Root() != root {
+		t.Error("unexpected root")
+	}
+	if !root.IsAncestorOf(child) || root.IsAncestorOf(parent) {
+		t.Error("unexpected IsAncestorOf result")
+	}
+	if err := root.AddInstance(inst); err != nil {
+		t.Error("failed re-add instance:", err)
+	}
+	if len(root.Instances) != 1 {
+		t.Error("instance added twice")
+	}
+
+	if err := inst.SetParent(parent); err != nil {
+		t.Error("failed set parent:", err)
+	}
+	if len(root.Instances) != 0 {
+		t.Error("instance not removed from root after setting parent")
+	}
+	if inst.Root() != nil {
+		t.Error("expected nil root")
+	}
+
+	if err := root.AddInstance(inst); err != nil {
+		t.Error("failed add instance:", err)
+	}
+	if err := inst.SetParent(nil); err != nil {
+		t.Error("failed set parent:", err)
+	}
+	if len(root.Instances) != 0 || inst.Root() != nil {
+		t.Error("instance not removed from root after setting nil parent")
+	}
+
+	other := &Root{}
+	root.AddInstance(inst)
+	other.AddInstance(inst)
+	if len(root.Instances) != 0 || len(other.Instances) != 1 || inst.Root() != other {
+		t.Error("instance not moved between roots")
+	}
+	if other.RemoveInstance(inst) != inst {
+		t.Error("failed remove instance")
+	}
+	if other.RemoveInstance(inst) != nil {
+		t.Error("removed instance not in root")
+	}
+	if inst.Root() != nil {
+		t.Error("expected nil root")
+	}
+}
+
+func TestRoot_GetService(t *testing.T) {
+	root := &Root{}
+	workspace := root.GetService("Workspace")
+	if workspace == nil || !workspace.IsService || workspace.ClassName != "Workspace" {
+		t.Fatal("service not created")
+	}
+	if workspace.Name() != "Workspace" {
+		t.Errorf("unexpected service name %q", workspace.Name())
+	}
+	if workspace.Root() != root {
+		t.Error("service not owned by root")
+	}
+	if root.GetService("Workspace") != workspace {
+		t.Error("expected existing service")
+	}
+	if len(root.Instances) != 1 {
+		t.Error("unexpected number of instances")
+	}
+
+	dup := NewInstance("Workspace", nil)
+	dup.IsService = true
+	if err := root.AddInstance(dup); err == nil {
+		t.Error("no error on adding duplicate service")
+	}
+	dup.IsService = false
+	if err := root.AddInstance(dup); err != nil {
+		t.Error("failed add instance:", err)
+	}
+
+	lighting := NewInstance("Lighting", nil)
+	root.Instances = append(root.Instances, lighting)
+	if root.GetService("Lighting") != lighting || !lighting.IsService {
+		t.Error("expected existing instance to become service")
+	}
+
+	root = &Root{ReferenceGenerator: &SequentialReferences{}}
+	taken := NewInstance("Folder", nil)
+	taken.Reference = "RBX0"
+	if err := root.AddInstance(taken); err != nil {
+		t.Fatal(err)
+	}
+	players := root.GetService("Players")
+	if players.Root() != root || players.Reference != "" {
+		t.Errorf("expected service with colliding reference to be added without reference, got %q", players.Reference)
+	}
+	if teams := root.GetService("Teams"); teams.Root() != root || teams.Reference != "RBX1" {
+		t.Errorf("expected generated reference, got %q", teams.Reference)
+	}
+}
+
+func TestRoot_FixTree(t *testing.T) {
+	parent := namedInst("Parent", nil)
+	inst := namedInst("Instance", parent)
+	root := &Root{Instances: []*Instance{inst}}
+	root.FixTree()
+	if inst.Parent() != nil || len(parent.Children) != 0 {
+		t.Error("instance not removed from parent")
+	}
+	if inst.Root() != root {
+		t.Error("instance not owned by root")
+	}
+	if rc := root.Copy(); rc.Instances[0].Root() != rc {
+		t.Error("copied instance not owned by copy")
+	}
+}
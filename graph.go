@@ -0,0 +1,110 @@
+package rbxfile
+
+import (
+	"sort"
+)
+
+// ReferenceEdge is a property of an instance that refers to another instance.
+type ReferenceEdge struct {
+	// Instance is the instance that has the property.
+	Instance *Instance
+	// Property is the name of the property.
+	Property string
+	// Target is the instance referred to by the property.
+	Target *Instance
+}
+
+// ReferenceGraph maps an instance to each property that refers to it.
+type ReferenceGraph map[*Instance][]ReferenceEdge
+
+// forEachReference calls fn for each non-nil reference property of inst, in
+// order of property name.
+func forEachReference(inst *Instance, fn func(edge ReferenceEdge)) {
+	var edges []ReferenceEdge
+	inst.Range(func(property string, value Value) bool {
+		if ref, ok := value.(ValueReference); ok && ref.Instance != nil {
+			edges = append(edges, ReferenceEdge{Instance: inst, Property: property, Target: ref.Instance})
+		}
+		return true
+	})
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].Property < edges[j].Property
+	})
+	for _, edge := range edges {
+		fn(edge)
+	}
+}
+
+// ReferenceGraph returns the references between instances in the tree of the
+// root. Targets may be outside the tree. The edges of each target are ordered
+// by the position of the referring instance in the tree, then by property
+// name.
+func (root *Root) ReferenceGraph() ReferenceGraph {
+	graph := ReferenceGraph{}
+	for _, inst := range root.GetDescendants() {
+		forEachReference(inst, func(edge ReferenceEdge) {
+			graph[edge.Target] = append(graph[edge.Target], edge)
+		})
+	}
+	return graph
+}
+
+// FindReferencesTo returns each property within the tree of the root that
+// refers to the given instance.
+func (root *Root) FindReferencesTo(target *Instance) []ReferenceEdge {
+	if target == nil {
+		return nil
+	}
+	var edges []ReferenceEdge
+	for _, inst := range root.GetDescendants() {
+		forEachReference(inst, func(edge ReferenceEdge) {
+			if edge.Target == target {
+				edges = append(edges, edge)
+			}
+		})
+	}
+	return edges
+}
+
+// FindDanglingReferences returns each property within the tree of the root
+// that refers to an instance outside of the tree. Such references usually
+// point to instances that were removed from the tree, or that were copied from
+// another tree.
+func (root *Root) FindDanglingReferences() []ReferenceEdge {
+	descendants := root.GetDescendants()
+	inTree := make(map[*Instance]bool, len(descendants))
+	for _, inst := range descendants {
+		inTree[inst] = true
+	}
+	var edges []ReferenceEdge
+	for _, inst := range descendants {
+		forEachReference(inst, func(edge ReferenceEdge) {
+			if !inTree[edge.Target] {
+				edges = append(edges, edge)
+			}
+		})
+	}
+	return edges
+}
+
+// Destroy removes the instance from its parent or root. Each property within
+// the root that refers to the instance or one of its descendants is set to a
+// nil reference. References from within the destroyed tree are left alone.
+func (inst *Instance) Destroy() {
+	root := inst.Root()
+	inst.detach()
+	if root == nil {
+		return
+	}
+	destroyed := map[*Instance]bool{inst: true}
+	for _, desc := range inst.GetDescendants() {
+		destroyed[desc] = true
+	}
+	for _, other := range root.GetDescendants() {
+		forEachReference(other, func(edge ReferenceEdge) {
+			if destroyed[edge.Target] {
+				other.Set(edge.Property, ValueReference{})
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package rbxfile
+
+import (
+	"testing"
+)
+
+func graphTestRoot() (root *Root, a, b, c, outside *Instance) {
+	a = namedInst("A", nil)
+	b = namedInst("B", a)
+	c = namedInst("C", nil)
+	outside = namedInst("Outside", nil)
+	a.Set("Target", ValueReference{Instance: c})
+	b.Set("Target", ValueReference{Instance: c})
+	b.Set("Other", ValueReference{Instance: outside})
+	c.Set("Adornee", ValueReference{Instance: b})
+	c.Set("Empty", ValueReference{})
+	root = &Root{}
+	root.AddInstance(a)
+	root.AddInstance(c)
+	return root, a, b, c, outside
+}
+
+func TestRoot_ReferenceGraph(t *testing.T) {
+	root, a, b, c, outside := graphTestRoot()
+	graph := root.ReferenceGraph()
+	if len(graph) != 3 {
+		t.Errorf("unexpected number of targets %d", len(graph))
+	}
+	edges := graph[c]
+	if len(edges) != 2 ||
+		edges[0] != (ReferenceEdge{Instance: a, Property: "Target", Target: c}) ||
+		edges[1] != (ReferenceEdge{Instance: b, Property: "Target", Target: c}) {
+		t.Errorf("unexpected edges to C: %v", edges)
+	}
+	if edges := graph[outside]; len(edges) != 1 || edges[0].Instance != b || edges[0].Property != "Other" {
+		t.Errorf("unexpected edges to Outside: %v", edges)
+	}
+
+	if edges := root.FindReferencesTo(b); len(edges) != 1 || edges[0].Instance != c || edges[0].Property != "Adornee" {
+		t.Errorf("unexpected references to B: %v", edges)
+	}
+	if edges := root.FindReferencesTo(nil); len(edges) != 0 {
+		t.Errorf("unexpected references to nil: %v", edges)
+	}
+
+	dangling := root.FindDanglingReferences()
+	if len(dangling) != 1 || dangling[0].Target != outside {
+		t.Errorf("unexpected dangling references: %v", dangling)
+	}
+}
+
+func TestInstance_Destroy(t *testing.T) {
+	root, a, b, c, _ := graphTestRoot()
+	a.Destroy()
+	if len(root.Instances) != 1 || root.Instances[0] != c {
+		t.Error("instance not removed from root")
+	}
+	if a.Root() != nil {
+		t.Error("expected nil root")
+	}
+	if v := c.Get("Adornee").(ValueReference); v.Instance != nil {
+		t.Error("reference to destroyed descendant not cleared")
+	}
+	if v := b.Get("Target").(ValueReference); v.Instance != c {
+		t.Error("reference from destroyed tree was changed")
+	}
+
+	root, _, b, c, _ = graphTestRoot()
+	c.Destroy()
+	if v := b.Get("Target").(ValueReference); v.Instance != nil {
+		t.Error("reference to destroyed instance not cleared")
+	}
+	b.Destroy()
+	if b.Parent() != nil {
+		t.Error("expected nil parent")
+	}
+	if len(root.FindDanglingReferences()) != 0 {
+		t.Error("unexpected dangling references")
+	}
+}
@@ -0,0 +1,253 @@
+package rbxfile
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Hash is a fingerprint of the content of an instance tree.
+type Hash [sha256.Size]byte
+
+// String returns the hash in hexadecimal.
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// Hash returns a fingerprint of the content of the instance and its
+// descendants. The hash is computed from the ClassName, IsService, and
+// properties of the instance, and the hashes of its children, in order. The
+// Reference field is not included, so that copies of a tree have the same
+// hash.
+//
+// A reference property is hashed by the positions of the instance that has
+// the property and of the referent within the tree of inst, so that
+// equivalent references in different copies of a tree have the same hash. A
+// referent that is outside the tree of inst is hashed only as being external,
+// so the hash does not depend on where the tree is located. Floats are
+// canonicalized so that negative zero is hashed as zero, and every NaN is
+// hashed the same.
+func (inst *Instance) Hash() Hash {
+	return newHasher().hash(inst)
+}
+
+// HashIndex returns the instances in the tree of the root, grouped by their
+// hash, as returned by Instance.Hash. Instances within a group have identical
+// subtrees. Each group is in tree order.
+func (root *Root) HashIndex() map[Hash][]*Instance {
+	h := newHasher()
+	index := map[Hash][]*Instance{}
+	for _, inst := range root.GetDescendants() {
+		sum := h.hash(inst)
+		index[sum] = append(index[sum], inst)
+	}
+	return index
+}
+
+// hasher computes the hashes of instances, remembering the hash of each
+// instance visited.
+type hasher struct {
+	sums map[*Instance]Hash
+	// open holds the references of each visited tree whose referent is
+	// outside of the tree.
+	open map[*Instance][]openRef
+	buf  [8]byte
+}
+
+// openRef is a reference property within a tree.
+type openRef struct {
+	// path holds the indices of the children leading from the root of the
+	// tree down to the instance that has the property.
+	path   []int
+	name   string
+	target *Instance
+}
+
+func newHasher() *hasher {
+	return &hasher{
+		sums: map[*Instance]Hash{},
+		open: map[*Instance][]openRef{},
+	}
+}
+
+// hash returns the hash of inst. The hash depends only on the tree of inst.
+func (h *hasher) hash(inst *Instance) Hash {
+	if sum, ok := h.sums[inst]; ok {
+		return sum
+	}
+	// Hash children first, so that the digest is not shared between
+	// recursive calls.
+	children := make([]Hash, len(inst.Children))
+	for i, child := range inst.Children {
+		children[i] = h.hash(child)
+	}
+
+	d := sha256.New()
+	h.writeString(d, inst.ClassName)
+	h.writeBool(d, inst.IsService)
+
+	var names []string
+	inst.Range(func(name string, value Value) bool {
+		names = append(names, name)
+		return true
+	})
+	sort.Strings(names)
+	// References whose referent is not known to be within the tree of inst.
+	var refs []openRef
+	h.writeUint(d, uint64(len(names)))
+	for _, name := range names {
+		value := inst.Properties[name]
+		h.writeString(d, name)
+		if value == nil {
+			d.Write([]byte{byte(TypeInvalid)})
+			continue
+		}
+		d.Write([]byte{byte(value.Type())})
+		if ref, ok := value.(ValueReference); ok {
+			// The referent is written with the references below.
+			if ref.Instance == nil {
+				d.Write([]byte{0})
+			} else {
+				d.Write([]byte{1})
+				refs = append(refs, openRef{name: name, target: ref.Instance})
+			}
+			continue
+		}
+		h.writeReflect(d, reflect.ValueOf(value))
+	}
+
+	h.writeUint(d, uint64(len(children)))
+	for _, sum := range children {
+		d.Write(sum[:])
+	}
+
+	// References that leave the tree of a child are hashed as external by the
+	// child. Those that lead to an instance within the tree of inst are
+	// written here, while the rest remain external.
+	for i, child := range inst.Children {
+		for _, ref := range h.open[child] {
+			ref.path = append([]int{i}, ref.path...)
+			refs = append(refs, ref)
+		}
+	}
+	var open, closed []openRef
+	var downs [][]int
+	for _, ref := range refs {
+		if down, ok := pathWithin(inst, ref.target); ok {
+			closed = append(closed, ref)
+			downs = append(downs, down)
+		} else {
+			open = append(open, ref)
+		}
+	}
+	h.writeUint(d, uint64(len(closed)))
+	for i, ref := range closed {
+		h.writeUint(d, uint64(len(ref.path)))
+		for _, j := range ref.path {
+			h.writeUint(d, uint64(j))
+		}
+		h.writeString(d, ref.name)
+		h.writeUint(d, uint64(len(downs[i])))
+		for _, j := range downs[i] {
+			h.writeUint(d, uint64(j))
+		}
+	}
+	if open != nil {
+		h.open[inst] = open
+	}
+
+	var sum Hash
+	d.Sum(sum[:0])
+	h.sums[inst] = sum
+	return sum
+}
+
+func (h *hasher) writeUint(d hash.Hash, v uint64) {
+	binary.BigEndian.PutUint64(h.buf[:], v)
+	d.Write(h.buf[:])
+}
+
+func (h *hasher) writeBool(d hash.Hash, v bool) {
+	if v {
+		d.Write([]byte{1})
+	} else {
+		d.Write([]byte{0})
+	}
+}
+
+func (h *hasher) writeString(d hash.Hash, s string) {
+	h.writeUint(d, uint64(len(s)))
+	d.Write([]byte(s))
+}
+
+func (h *hasher) writeFloat(d hash.Hash, f float64) {
+	switch {
+	case f == 0:
+		f = 0
+	case math.IsNaN(f):
+		f = math.NaN()
+	}
+	h.writeUint(d, math.Float64bits(f))
+}
+
+// pathWithin returns the indices of the children leading from root down to
+// target. Returns false if target is not root or a descendant of root.
+func pathWithin(root, target *Instance) (path []int, ok bool) {
+	for t := target; t != nil; t = t.parent {
+		if t == root {
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, true
+		}
+		if t.parent != nil {
+			path = append(path, childIndex(t.parent, t))
+		}
+	}
+	return nil, false
+}
+
+// childIndex returns the index of child within the children of parent.
+func childIndex(parent, child *Instance) int {
+	for i, c := range parent.Children {
+		if c == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeReflect writes the underlying data of a value.
+func (h *hasher) writeReflect(d hash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		h.writeFloat(d, v.Float())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			h.writeReflect(d, v.Field(i))
+		}
+	case reflect.Slice:
+		h.writeUint(d, uint64(v.Len()))
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			d.Write(v.Bytes())
+			return
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			h.writeReflect(d, v.Index(i))
+		}
+	case reflect.Bool:
+		h.writeBool(d, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.writeUint(d, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		h.writeUint(d, v.Uint())
+	case reflect.String:
+		h.writeString(d, v.String())
+	}
+}
@@ -0,0 +1,125 @@
+package rbxfile
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInstance_Hash(t *testing.T) {
+	a, b := equalTestTree(), equalTestTree()
+	b.Reference = "RBXOther"
+	if a.Hash() != b.Hash() {
+		t.Error("expected equal hashes")
+	}
+	if a.Hash() != a.Clone().Hash() {
+		t.Error("expected clone to have equal hash")
+	}
+	if a.Hash() == a.Children[0].Hash() {
+		t.Error("expected different hashes")
+	}
+	if len(a.Hash().String()) != 64 {
+		t.Errorf("unexpected hash string %q", a.Hash())
+	}
+
+	b = equalTestTree()
+	b.Children[1].Set("Target", ValueReference{Instance: b.Children[1]})
+	if a.Hash() == b.Hash() {
+		t.Error("expected reference position to affect hash")
+	}
+	b.Children[1].Set("Target", ValueReference{Instance: NewInstance("Outside", nil)})
+	if a.Hash() == b.Hash() {
+		t.Error("expected external reference to affect hash")
+	}
+
+	b = equalTestTree()
+	b.Children[0].Set("Data", ValueSharedString("other"))
+	if a.Hash() == b.Hash() {
+		t.Error("expected property value to affect hash")
+	}
+
+	b = equalTestTree()
+	b.Children[0], b.Children[1] = b.Children[1], b.Children[0]
+	if a.Hash() == b.Hash() {
+		t.Error("expected child order to affect hash")
+	}
+
+	x, y := NewInstance("Part", nil), NewInstance("Part", nil)
+	x.Set("Value", ValueDouble(0))
+	y.Set("Value", ValueDouble(math.Copysign(0, -1)))
+	if x.Hash() != y.Hash() {
+		t.Error("expected negative zero to be canonicalized")
+	}
+	x.Set("Value", ValueFloat(math.NaN()))
+	y.Set("Value", ValueFloat(float32(-math.NaN())))
+	if x.Hash() != y.Hash() {
+		t.Error("expected NaN to be canonicalized")
+	}
+}
+
+func TestRoot_HashIndex(t *testing.T) {
+	root := &Root{Instances: []*Instance{equalTestTree(), equalTestTree(), namedInst("Folder", nil)}}
+	index := root.HashIndex()
+	models := index[root.Instances[0].Hash()]
+	if len(models) != 2 || models[0] != root.Instances[0] || models[1] != root.Instances[1] {
+		t.Errorf("unexpected group of models: %v", models)
+	}
+	parts := index[root.Instances[0].Children[0].Hash()]
+	if len(parts) != 2 {
+		t.Errorf("unexpected group of parts: %v", parts)
+	}
+	if n := len(index[root.Instances[2].Hash()]); n != 1 {
+		t.Errorf("unexpected group size %d", n)
+	}
+	total := 0
+	for _, group := range index {
+		total += len(group)
+	}
+	if total != len(root.GetDescendants()) {
+		t.Errorf("unexpected number of indexed instances %d", total)
+	}
+}
+
+func TestRoot_HashIndexExternalReferences(t *testing.T) {
+	folder := namedInst("Folder", nil)
+	target := namedInst("Target", folder)
+	model := func() *Instance {
+		m := namedInst("Model", nil)
+		part := namedInst("Part", m)
+		part.Set("Outside", ValueReference{Instance: target})
+		part.Set("Inside", ValueReference{Instance: namedInst("Child", m)})
+		return m
+	}
+	a := model()
+	a.SetParent(folder)
+	b := model()
+	b.SetParent(namedInst("Nested", folder))
+	root := &Root{Instances: []*Instance{folder}}
+
+	index := root.HashIndex()
+	group := index[a.Hash()]
+	if len(group) != 2 || group[0] != a || group[1] != b {
+		t.Errorf("expected copies to share a hash: %v", group)
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("expected copies at different positions to have equal hashes")
+	}
+	if group := index[a.Children[0].Hash()]; len(group) != 2 {
+		t.Errorf("expected parts to share a hash: %v", group)
+	}
+
+	// The position of an external referent does not matter, but the position
+	// of an internal referent does.
+	c := model()
+	c.Children[0].Set("Outside", ValueReference{Instance: folder})
+	if a.Hash() != c.Hash() {
+		t.Error("expected external referents to be hashed alike")
+	}
+	c.Children[0].Set("Outside", ValueReference{Instance: c.Children[1]})
+	if a.Hash() == c.Hash() {
+		t.Error("expected internal referent to affect hash")
+	}
+	c.Children[0].Set("Outside", ValueReference{Instance: c.Children[0]})
+	if a.Hash() == c.Hash() || c.Hash() == model().Hash() {
+		t.Error("expected position of internal referent to affect hash")
+	}
+}
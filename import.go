@@ -0,0 +1,148 @@
+package rbxfile
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MetadataPolicy determines how the metadata of an imported root is merged
+// into the metadata of the destination root.
+type MetadataPolicy int
+
+const (
+	// MetadataKeep adds entries that are missing from the destination, and
+	// keeps the existing value of entries present in both roots.
+	MetadataKeep MetadataPolicy = iota
+	// MetadataReplace adds all entries, replacing the existing value of
+	// entries present in both roots.
+	MetadataReplace
+	// MetadataIgnore leaves the metadata of the destination unchanged.
+	MetadataIgnore
+)
+
+// ImportOptions configures Root.Import.
+type ImportOptions struct {
+	// Metadata determines how metadata is merged.
+	Metadata MetadataPolicy
+
+	// ReferenceGenerator is used to generate references for imported
+	// instances whose references collide with the destination. If nil, then
+	// the ReferenceGenerator of the destination root is used.
+	ReferenceGenerator ReferenceGenerator
+}
+
+// Import copies the instances of src into the root. If parent is nil, then
+// the copies are added as instances of the root. Otherwise, they are added as
+// children of parent, which must be in the tree of the root. Returns the
+// copies of the instances of src, in order. src is not modified.
+//
+// A copied instance retains its reference, unless the reference is empty, or
+// collides with another instance in the root, in which case a new reference is
+// generated. Reference properties that point to an instance within src are
+// rewritten to point to the corresponding copy. Other references continue to
+// point to the same instance. Property values, including the data of
+// SharedString values, are copied. As with Clone, the Extra field of each
+// instance is copied by reference, so it is shared between src and the root.
+//
+// An error is returned, and the root is left unchanged, if parent is not in the
+// tree of the root, or if an instance of src is a service that already exists
+// in the root when parent is nil.
+func (root *Root) Import(src *Root, parent *Instance, opts ImportOptions) ([]*Instance, error) {
+	if src == nil {
+		return nil, errors.New("source root is nil")
+	}
+	if parent != nil && parent.Root() != root {
+		return nil, errors.New("parent is not in the tree of the root")
+	}
+	if parent == nil {
+		services := map[string]bool{}
+		for _, inst := range src.Instances {
+			if !inst.IsService {
+				continue
+			}
+			if services[inst.ClassName] || root.findService(inst.ClassName) != nil {
+				return nil, fmt.Errorf("service %s already exists", inst.ClassName)
+			}
+			services[inst.ClassName] = true
+		}
+	}
+
+	gen := opts.ReferenceGenerator
+	if gen == nil {
+		gen = root.ReferenceGenerator
+	}
+	root.buildIndex()
+	taken := make(map[string]bool, len(root.refs))
+	for ref := range root.refs {
+		taken[ref] = true
+	}
+	copies := map[*Instance]*Instance{}
+	var propRefs []PropRef
+	var importTree func(inst *Instance) *Instance
+	importTree = func(inst *Instance) *Instance {
+		inst.LoadProperties()
+		ref := inst.Reference
+		for IsEmptyReference(ref) || taken[ref] {
+			ref = generateReference(gen)
+		}
+		taken[ref] = true
+		clone := &Instance{
+			ClassName:  inst.ClassName,
+			Reference:  ref,
+			IsService:  inst.IsService,
+			Extra:      inst.Extra,
+			Children:   make([]*Instance, len(inst.Children)),
+			Properties: make(map[string]Value, len(inst.Properties)),
+		}
+		copies[inst] = clone
+		for name, value := range inst.Properties {
+			if value, ok := value.(ValueReference); ok {
+				// Reference is unused; the referent is resolved through
+				// copies.
+				propRefs = append(propRefs, PropRef{Instance: clone, Property: name})
+				clone.Properties[name] = value
+				continue
+			}
+			clone.Properties[name] = value.Copy()
+		}
+		for i, child := range inst.Children {
+			c := importTree(child)
+			clone.Children[i] = c
+			c.parent = clone
+		}
+		return clone
+	}
+	imported := make([]*Instance, len(src.Instances))
+	for i, inst := range src.Instances {
+		imported[i] = importTree(inst)
+	}
+	for _, propRef := range propRefs {
+		value := propRef.Instance.Properties[propRef.Property].(ValueReference)
+		if c, ok := copies[value.Instance]; ok {
+			propRef.Instance.Properties[propRef.Property] = ValueReference{Instance: c}
+		}
+	}
+
+	for _, inst := range imported {
+		if parent == nil {
+			root.Instances = append(root.Instances, inst)
+			inst.root = root
+			root.indexTree(inst)
+		} else {
+			parent.addChild(inst)
+		}
+	}
+
+	if opts.Metadata != MetadataIgnore && len(src.Metadata) > 0 {
+		if root.Metadata == nil {
+			root.Metadata = make(map[string]string, len(src.Metadata))
+		}
+		for key, value := range src.Metadata {
+			if _, ok := root.Metadata[key]; ok && opts.Metadata == MetadataKeep {
+				continue
+			}
+			root.Metadata[key] = value
+		}
+	}
+	return imported, nil
+}
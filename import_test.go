@@ -0,0 +1,143 @@
+package rbxfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoot_Import(t *testing.T) {
+	place := &Root{Metadata: map[string]string{"A": "place"}}
+	workspace := place.GetService("Workspace")
+	existing := refInst("Existing", "RBX1", nil)
+	workspace.AddChild(existing)
+
+	outside := namedInst("Outside", nil)
+	model := refInst("Model", "RBX1", nil)
+	part := refInst("Part", "RBX2", model)
+	part.Set("Data", ValueSharedString("shared"))
+	part.Set("Self", ValueReference{Instance: model})
+	part.Set("Outside", ValueReference{Instance: outside})
+	src := &Root{
+		Instances: []*Instance{model},
+		Metadata:  map[string]string{"A": "model", "B": "model"},
+	}
+
+	imported, err := place.Import(src, workspace, ImportOptions{})
+	if err != nil {
+		t.Fatal("failed import:", err)
+	}
+	if len(imported) != 1 || imported[0] == model {
+		t.Fatal("expected copied instance")
+	}
+	mc := imported[0]
+	if mc.Parent() != workspace || len(workspace.Children) != 2 {
+		t.Error("copy not added to parent")
+	}
+	if mc.Reference == "RBX1" || existing.Reference != "RBX1" || model.Reference != "RBX1" {
+		t.Error("colliding reference not remapped")
+	}
+	pc := mc.Children[0]
+	if pc.Reference != "RBX2" {
+		t.Error("unexpected remapped reference")
+	}
+	if place.GetByReference(mc.Reference) != mc || place.GetByReference("RBX2") != pc {
+		t.Error("imported instances not indexed")
+	}
+	if v := pc.Get("Self").(ValueReference); v.Instance != mc {
+		t.Error("internal reference not rewritten")
+	}
+	if v := pc.Get("Outside").(ValueReference); v.Instance != outside {
+		t.Error("external reference changed")
+	}
+	data := pc.Get("Data").(ValueSharedString)
+	if !bytes.Equal(data, []byte("shared")) {
+		t.Error("shared string not carried over")
+	}
+	data[0] = 'S'
+	if v := part.Get("Data").(ValueSharedString); v[0] != 's' {
+		t.Error("shared string not copied")
+	}
+	if place.Metadata["A"] != "place" || place.Metadata["B"] != "model" {
+		t.Errorf("unexpected metadata %v", place.Metadata)
+	}
+
+	if _, err := place.Import(src, nil, ImportOptions{Metadata: MetadataReplace}); err != nil {
+		t.Fatal("failed import:", err)
+	}
+	if len(place.Instances) != 2 || place.Instances[1].Root() != place {
+		t.Error("copy not added to root")
+	}
+	if place.Metadata["A"] != "model" {
+		t.Errorf("unexpected metadata %v", place.Metadata)
+	}
+
+	services := &Root{Metadata: map[string]string{"C": "services"}}
+	services.GetService("Workspace")
+	if _, err := place.Import(services, nil, ImportOptions{Metadata: MetadataIgnore}); err == nil {
+		t.Error("no error on importing existing service")
+	}
+	if _, err := place.Import(services, outside, ImportOptions{}); err == nil {
+		t.Error("no error on parent outside of root")
+	}
+	if _, err := place.Import(services, workspace, ImportOptions{Metadata: MetadataIgnore}); err != nil {
+		t.Error("failed import:", err)
+	}
+	if _, ok := place.Metadata["C"]; ok {
+		t.Error("metadata not ignored")
+	}
+}
+
+func TestRoot_ImportCollision(t *testing.T) {
+	// Every reference of src collides with an instance of the destination.
+	dst := &Root{ReferenceGenerator: &SequentialReferences{Prefix: "NEW"}}
+	for _, ref := range []string{"RBX0", "RBX1", "RBX2"} {
+		dst.AddInstance(refInst("Folder", ref, nil))
+	}
+	model := refInst("Model", "RBX0", nil)
+	a := refInst("A", "RBX1", model)
+	b := refInst("B", "RBX2", model)
+	model.Set("PrimaryPart", ValueReference{Instance: b})
+	a.Set("Next", ValueReference{Instance: b})
+	b.Set("Self", ValueReference{Instance: b})
+	extra := &struct{}{}
+	model.Extra = extra
+	src := &Root{Instances: []*Instance{model}}
+	src.FixTree()
+
+	imported, err := dst.Import(src, nil, ImportOptions{})
+	if err != nil {
+		t.Fatal("failed import:", err)
+	}
+	mc := imported[0]
+	bc := mc.Children[1]
+	for i, inst := range []*Instance{mc, mc.Children[0], bc} {
+		if want := "NEW" + string('0'+rune(i)); inst.Reference != want {
+			t.Errorf("expected reference %s, got %s", want, inst.Reference)
+		}
+		if dst.GetByReference(inst.Reference) != inst {
+			t.Errorf("reference %s not indexed", inst.Reference)
+		}
+	}
+	refs := 0
+	for _, inst := range []*Instance{mc, mc.Children[0], bc} {
+		for name, value := range inst.Properties {
+			v, ok := value.(ValueReference)
+			if !ok {
+				continue
+			}
+			refs++
+			if v.Instance != bc || dst.GetByReference(v.Instance.Reference) != bc {
+				t.Errorf("%s: expected reference to copy in new tree", name)
+			}
+		}
+	}
+	if refs != 3 {
+		t.Errorf("expected 3 reference properties, got %d", refs)
+	}
+	if dst.GetByReference("RBX2").Name() != "Folder" {
+		t.Error("existing reference changed")
+	}
+	if mc.Extra != extra {
+		t.Error("expected Extra to be shared")
+	}
+}
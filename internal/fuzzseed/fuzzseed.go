@@ -0,0 +1,61 @@
+//go:build go1.18
+// +build go1.18
+
+// Package fuzzseed provides the seed corpus shared by the fuzz tests of the
+// format packages.
+package fuzzseed
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// StripDirectives removes the leading directives of an rbxtestfiles input.
+func StripDirectives(b []byte) []byte {
+	r := bufio.NewReader(bytes.NewReader(b))
+	n := 0
+	for {
+		c, err := r.Peek(1)
+		if err != nil || c[0] != '#' {
+			break
+		}
+		line, err := r.ReadString('\n')
+		n += len(line)
+		if strings.TrimSpace(line) == "#begin-content" || err != nil {
+			break
+		}
+	}
+	return b[n:]
+}
+
+// Add adds files with the given extensions from the testdata directory of the
+// calling package to the seed corpus. If the RBXFILE_TESTFILES environment
+// variable is set, then inputs from that rbxtestfiles directory are also
+// added.
+func Add(f *testing.F, exts ...string) {
+	dirs := []string{"testdata"}
+	if dir := os.Getenv("RBXFILE_TESTFILES"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			for _, ext := range exts {
+				if filepath.Ext(path) == ext {
+					if b, err := ioutil.ReadFile(path); err == nil {
+						f.Add(StripDirectives(b))
+					}
+					break
+				}
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package json
+
+import (
+	"github.com/robloxapi/rbxfile"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzDecode(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.json"))
+	for _, file := range files {
+		if b, err := ioutil.ReadFile(file); err == nil {
+			f.Add(b)
+		}
+	}
+	f.Add([]byte(`{"rbxfile_version":0,"instances":[]}`))
+	// Values with missing or mistyped fields.
+	f.Add([]byte(`{"rbxfile_version":0,"instances":[{"class_name":"Frame","properties":{"Size":{"type":"UDim2","value":{"x":{"scale":1},"y":null}}}}]}`))
+	f.Add([]byte(`{"rbxfile_version":0,"instances":[{"class_name":"Part","properties":{"CFrame":{"type":"CFrame","value":{"position":{"x":1,"y":2,"z":3},"rotation":[1,"0"]}}}}]}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		DecodeLimits(b, rbxfile.Limits{MaxTotalBytes: 1 << 22})
+	})
+}
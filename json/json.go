@@ -16,11 +16,29 @@ func Encode(root *rbxfile.Root) (b []byte, err error) {
 }
 
 func Decode(b []byte) (root *rbxfile.Root, err error) {
+	return DecodeLimits(b, rbxfile.Limits{})
+}
+
+// DecodeLimits is like Decode, but bounds the resources used while decoding.
+// MaxTotalBytes limits the length of b. The remaining limits are checked
+// against the decoded JSON before any instances are created. If a limit is
+// exceeded, a rbxfile.ErrLimit is returned. MaxChunkSize is ignored.
+func DecodeLimits(b []byte, limits rbxfile.Limits) (root *rbxfile.Root, err error) {
+	if err = limits.Check(rbxfile.LimitTotalBytes, int64(len(b))); err != nil {
+		return nil, err
+	}
 	var v interface{}
 	err = json.Unmarshal(b, &v)
 	if err != nil {
 		return nil, err
 	}
+	var instances []interface{}
+	if indexJSON(v, "instances", &instances) {
+		c := limitChecker{limits: limits}
+		if err = c.checkInstances(instances, 1); err != nil {
+			return nil, err
+		}
+	}
 	root, ok := RootFromJSONInterface(v)
 	if !ok {
 		return nil, errors.New("invalid JSON Root object")
@@ -28,6 +46,85 @@ func Decode(b []byte) (root *rbxfile.Root, err error) {
 	return root, nil
 }
 
+// limitChecker checks a generic interface produced by json.Unmarshal against
+// a set of limits.
+type limitChecker struct {
+	limits    rbxfile.Limits
+	instCount int64
+	propCount int64
+}
+
+func (c *limitChecker) checkInstances(instances []interface{}, depth int) error {
+	if len(instances) == 0 {
+		return nil
+	}
+	if err := c.limits.Check(rbxfile.LimitDepth, int64(depth)); err != nil {
+		return err
+	}
+	for _, iinst := range instances {
+		c.instCount++
+		if err := c.limits.Check(rbxfile.LimitInstances, c.instCount); err != nil {
+			return err
+		}
+
+		var className string
+		indexJSON(iinst, "class_name", &className)
+		if err := c.checkString(className); err != nil {
+			return err
+		}
+
+		var properties map[string]interface{}
+		indexJSON(iinst, "properties", &properties)
+		c.propCount += int64(len(properties))
+		if err := c.limits.Check(rbxfile.LimitProperties, c.propCount); err != nil {
+			return err
+		}
+		for name, iprop := range properties {
+			if err := c.checkString(name); err != nil {
+				return err
+			}
+			if err := c.checkValue(iprop); err != nil {
+				return err
+			}
+		}
+
+		var children []interface{}
+		indexJSON(iinst, "children", &children)
+		if err := c.checkInstances(children, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *limitChecker) checkString(s string) error {
+	return c.limits.Check(rbxfile.LimitStringLength, int64(len(s)))
+}
+
+// checkValue checks every string within a property.
+func (c *limitChecker) checkValue(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		return c.checkString(v)
+	case []interface{}:
+		for _, v := range v {
+			if err := c.checkValue(v); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range v {
+			if err := c.checkString(k); err != nil {
+				return err
+			}
+			if err := c.checkValue(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // The current version of the schema.
 const jsonVersion = 0
 
@@ -138,6 +235,7 @@ func RootFromJSONInterface(iroot interface{}) (root *rbxfile.Root, ok bool) {
 			}
 			root.Instances = append(root.Instances, inst)
 		}
+		root.FixTree()
 		for _, pr := range propRefs {
 			pr.Instance.Properties[pr.Property] = rbxfile.ValueReference{
 				Instance: refs[pr.Reference],
@@ -160,6 +258,7 @@ func InstanceToJSONInterface(inst *rbxfile.Instance, refs rbxfile.References) in
 	iinst["class_name"] = inst.ClassName
 	iinst["reference"] = refs.Get(inst)
 	iinst["is_service"] = inst.IsService
+	inst.LoadProperties()
 	properties := make(map[string]interface{}, len(inst.Properties))
 	for name, prop := range inst.Properties {
 		iprop := make(map[string]interface{}, 2)
@@ -405,6 +504,11 @@ func ValueToJSONInterface(value rbxfile.Value, refs rbxfile.References) interfac
 		bw := base64.NewEncoder(base64.StdEncoding, &buf)
 		bw.Write([]byte(value))
 		return buf.String()
+	case rbxfile.ValueContentData:
+		return map[string]interface{}{
+			"hash": value.Hash,
+			"data": base64.StdEncoding.EncodeToString(value.Data),
+		}
 	}
 	return nil
 }
@@ -474,55 +578,57 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 		}
 		return rbxfile.ValueDouble(v)
 	case rbxfile.TypeUDim:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var scale, offset float64
+		if !indexJSON(ivalue, "scale", &scale) ||
+			!indexJSON(ivalue, "offset", &offset) {
 			return nil
 		}
 		return rbxfile.ValueUDim{
-			Scale:  float32(v["scale"].(float64)),
-			Offset: int32(v["offset"].(float64)),
+			Scale:  float32(scale),
+			Offset: int32(offset),
 		}
 	case rbxfile.TypeUDim2:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var ix, iy interface{}
+		if !indexJSON(ivalue, "x", &ix) || !indexJSON(ivalue, "y", &iy) {
 			return nil
 		}
-		return rbxfile.ValueUDim2{
-			X: ValueFromJSONInterface(rbxfile.TypeUDim, v["x"]).(rbxfile.ValueUDim),
-			Y: ValueFromJSONInterface(rbxfile.TypeUDim, v["y"]).(rbxfile.ValueUDim),
+		x, okx := ValueFromJSONInterface(rbxfile.TypeUDim, ix).(rbxfile.ValueUDim)
+		y, oky := ValueFromJSONInterface(rbxfile.TypeUDim, iy).(rbxfile.ValueUDim)
+		if !okx || !oky {
+			return nil
 		}
+		return rbxfile.ValueUDim2{X: x, Y: y}
 	case rbxfile.TypeRay:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var iorigin, idirection interface{}
+		if !indexJSON(ivalue, "origin", &iorigin) ||
+			!indexJSON(ivalue, "direction", &idirection) {
 			return nil
 		}
-		return rbxfile.ValueRay{
-			Origin:    ValueFromJSONInterface(rbxfile.TypeVector3, v["origin"]).(rbxfile.ValueVector3),
-			Direction: ValueFromJSONInterface(rbxfile.TypeVector3, v["direction"]).(rbxfile.ValueVector3),
+		origin, oko := ValueFromJSONInterface(rbxfile.TypeVector3, iorigin).(rbxfile.ValueVector3)
+		direction, okd := ValueFromJSONInterface(rbxfile.TypeVector3, idirection).(rbxfile.ValueVector3)
+		if !oko || !okd {
+			return nil
 		}
+		return rbxfile.ValueRay{Origin: origin, Direction: direction}
 	case rbxfile.TypeFaces:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var value rbxfile.ValueFaces
+		if !indexJSON(ivalue, "right", &value.Right) ||
+			!indexJSON(ivalue, "top", &value.Top) ||
+			!indexJSON(ivalue, "back", &value.Back) ||
+			!indexJSON(ivalue, "left", &value.Left) ||
+			!indexJSON(ivalue, "bottom", &value.Bottom) ||
+			!indexJSON(ivalue, "front", &value.Front) {
 			return nil
 		}
-		return rbxfile.ValueFaces{
-			Right:  v["right"].(bool),
-			Top:    v["top"].(bool),
-			Back:   v["back"].(bool),
-			Left:   v["left"].(bool),
-			Bottom: v["bottom"].(bool),
-			Front:  v["front"].(bool),
-		}
+		return value
 	case rbxfile.TypeAxes:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var value rbxfile.ValueAxes
+		if !indexJSON(ivalue, "x", &value.X) ||
+			!indexJSON(ivalue, "y", &value.Y) ||
+			!indexJSON(ivalue, "z", &value.Z) {
 			return nil
 		}
-		return rbxfile.ValueAxes{
-			X: v["x"].(bool),
-			Y: v["y"].(bool),
-			Z: v["z"].(bool),
-		}
+		return value
 	case rbxfile.TypeBrickColor:
 		v, ok := ivalue.(float64)
 		if !ok {
@@ -530,51 +636,60 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 		}
 		return rbxfile.ValueBrickColor(uint32(v))
 	case rbxfile.TypeColor3:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var r, g, b float64
+		if !indexJSON(ivalue, "r", &r) ||
+			!indexJSON(ivalue, "g", &g) ||
+			!indexJSON(ivalue, "b", &b) {
 			return nil
 		}
 		return rbxfile.ValueColor3{
-			R: float32(v["r"].(float64)),
-			G: float32(v["g"].(float64)),
-			B: float32(v["b"].(float64)),
+			R: float32(r),
+			G: float32(g),
+			B: float32(b),
 		}
 	case rbxfile.TypeVector2:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var x, y float64
+		if !indexJSON(ivalue, "x", &x) || !indexJSON(ivalue, "y", &y) {
 			return nil
 		}
 		return rbxfile.ValueVector2{
-			X: float32(v["x"].(float64)),
-			Y: float32(v["y"].(float64)),
+			X: float32(x),
+			Y: float32(y),
 		}
 	case rbxfile.TypeVector3:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var x, y, z float64
+		if !indexJSON(ivalue, "x", &x) ||
+			!indexJSON(ivalue, "y", &y) ||
+			!indexJSON(ivalue, "z", &z) {
 			return nil
 		}
 		return rbxfile.ValueVector3{
-			X: float32(v["x"].(float64)),
-			Y: float32(v["y"].(float64)),
-			Z: float32(v["z"].(float64)),
+			X: float32(x),
+			Y: float32(y),
+			Z: float32(z),
 		}
 	case rbxfile.TypeCFrame:
-		v, ok := ivalue.(map[string]interface{})
+		var iposition interface{}
+		if !indexJSON(ivalue, "position", &iposition) {
+			return nil
+		}
+		position, ok := ValueFromJSONInterface(rbxfile.TypeVector3, iposition).(rbxfile.ValueVector3)
 		if !ok {
 			return nil
 		}
 		value := rbxfile.ValueCFrame{
-			Position: ValueFromJSONInterface(rbxfile.TypeVector3, v["position"]).(rbxfile.ValueVector3),
+			Position: position,
 		}
-		irotation, ok := v["rotation"].([]interface{})
-		if !ok {
+		var irotation []interface{}
+		if !indexJSON(ivalue, "rotation", &irotation) {
 			return value
 		}
-		for i, irot := range irotation {
-			if i >= len(value.Rotation) {
+		for i := range value.Rotation {
+			var rot float64
+			if !indexJSON(irotation, i, &rot) {
 				break
 			}
-			value.Rotation[i] = float32(irot.(float64))
+			value.Rotation[i] = float32(rot)
 		}
 		return value
 	case rbxfile.TypeToken:
@@ -592,23 +707,25 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 		// ValueString.
 		return rbxfile.ValueString(v)
 	case rbxfile.TypeVector3int16:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var x, y, z float64
+		if !indexJSON(ivalue, "x", &x) ||
+			!indexJSON(ivalue, "y", &y) ||
+			!indexJSON(ivalue, "z", &z) {
 			return nil
 		}
 		return rbxfile.ValueVector3int16{
-			X: int16(v["x"].(float64)),
-			Y: int16(v["y"].(float64)),
-			Z: int16(v["z"].(float64)),
+			X: int16(x),
+			Y: int16(y),
+			Z: int16(z),
 		}
 	case rbxfile.TypeVector2int16:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var x, y float64
+		if !indexJSON(ivalue, "x", &x) || !indexJSON(ivalue, "y", &y) {
 			return nil
 		}
 		return rbxfile.ValueVector2int16{
-			X: int16(v["x"].(float64)),
-			Y: int16(v["y"].(float64)),
+			X: int16(x),
+			Y: int16(y),
 		}
 	case rbxfile.TypeNumberSequence:
 		v, ok := ivalue.([]interface{})
@@ -617,14 +734,16 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 		}
 		value := make(rbxfile.ValueNumberSequence, len(v))
 		for i, insk := range v {
-			insk, ok := insk.(map[string]interface{})
-			if !ok {
+			var t, nv, e float64
+			if !indexJSON(insk, "time", &t) ||
+				!indexJSON(insk, "value", &nv) ||
+				!indexJSON(insk, "envelope", &e) {
 				continue
 			}
 			value[i] = rbxfile.ValueNumberSequenceKeypoint{
-				Time:     float32(insk["time"].(float64)),
-				Value:    float32(insk["value"].(float64)),
-				Envelope: float32(insk["envelope"].(float64)),
+				Time:     float32(t),
+				Value:    float32(nv),
+				Envelope: float32(e),
 			}
 		}
 		return value
@@ -635,57 +754,72 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 		}
 		value := make(rbxfile.ValueColorSequence, len(v))
 		for i, icsk := range v {
-			icsk, ok := icsk.(map[string]interface{})
+			var t, e float64
+			var icv interface{}
+			if !indexJSON(icsk, "time", &t) ||
+				!indexJSON(icsk, "value", &icv) ||
+				!indexJSON(icsk, "envelope", &e) {
+				continue
+			}
+			cv, ok := ValueFromJSONInterface(rbxfile.TypeColor3, icv).(rbxfile.ValueColor3)
 			if !ok {
 				continue
 			}
 			value[i] = rbxfile.ValueColorSequenceKeypoint{
-				Time:     float32(icsk["time"].(float64)),
-				Value:    ValueFromJSONInterface(rbxfile.TypeColor3, icsk["value"]).(rbxfile.ValueColor3),
-				Envelope: float32(icsk["envelope"].(float64)),
+				Time:     float32(t),
+				Value:    cv,
+				Envelope: float32(e),
 			}
 		}
 		return value
 	case rbxfile.TypeNumberRange:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var min, max float64
+		if !indexJSON(ivalue, "min", &min) || !indexJSON(ivalue, "max", &max) {
 			return nil
 		}
 		return rbxfile.ValueNumberRange{
-			Min: float32(v["min"].(float64)),
-			Max: float32(v["max"].(float64)),
+			Min: float32(min),
+			Max: float32(max),
 		}
 	case rbxfile.TypeRect2D:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var imin, imax interface{}
+		if !indexJSON(ivalue, "min", &imin) || !indexJSON(ivalue, "max", &imax) {
 			return nil
 		}
-		return rbxfile.ValueRect2D{
-			Min: ValueFromJSONInterface(rbxfile.TypeVector2, v["min"]).(rbxfile.ValueVector2),
-			Max: ValueFromJSONInterface(rbxfile.TypeVector2, v["max"]).(rbxfile.ValueVector2),
+		min, okmin := ValueFromJSONInterface(rbxfile.TypeVector2, imin).(rbxfile.ValueVector2)
+		max, okmax := ValueFromJSONInterface(rbxfile.TypeVector2, imax).(rbxfile.ValueVector2)
+		if !okmin || !okmax {
+			return nil
 		}
+		return rbxfile.ValueRect2D{Min: min, Max: max}
 	case rbxfile.TypePhysicalProperties:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var value rbxfile.ValuePhysicalProperties
+		var density, friction, elasticity, frictionWeight, elasticityWeight float64
+		if !indexJSON(ivalue, "custom_physics", &value.CustomPhysics) ||
+			!indexJSON(ivalue, "density", &density) ||
+			!indexJSON(ivalue, "friction", &friction) ||
+			!indexJSON(ivalue, "elasticity", &elasticity) ||
+			!indexJSON(ivalue, "friction_weight", &frictionWeight) ||
+			!indexJSON(ivalue, "elasticity_weight", &elasticityWeight) {
 			return nil
 		}
-		return rbxfile.ValuePhysicalProperties{
-			CustomPhysics:    v["custom_physics"].(bool),
-			Density:          float32(v["density"].(float64)),
-			Friction:         float32(v["friction"].(float64)),
-			Elasticity:       float32(v["elasticity"].(float64)),
-			FrictionWeight:   float32(v["friction_weight"].(float64)),
-			ElasticityWeight: float32(v["elasticity_weight"].(float64)),
-		}
+		value.Density = float32(density)
+		value.Friction = float32(friction)
+		value.Elasticity = float32(elasticity)
+		value.FrictionWeight = float32(frictionWeight)
+		value.ElasticityWeight = float32(elasticityWeight)
+		return value
 	case rbxfile.TypeColor3uint8:
-		v, ok := ivalue.(map[string]interface{})
-		if !ok {
+		var r, g, b float64
+		if !indexJSON(ivalue, "r", &r) ||
+			!indexJSON(ivalue, "g", &g) ||
+			!indexJSON(ivalue, "b", &b) {
 			return nil
 		}
 		return rbxfile.ValueColor3uint8{
-			R: byte(v["r"].(float64)),
-			G: byte(v["g"].(float64)),
-			B: byte(v["b"].(float64)),
+			R: byte(r),
+			G: byte(g),
+			B: byte(b),
 		}
 	case rbxfile.TypeInt64:
 		v, ok := ivalue.(float64)
@@ -705,6 +839,21 @@ func ValueFromJSONInterface(typ rbxfile.Type, ivalue interface{}) (value rbxfile
 			return rbxfile.ValueSharedString(v)
 		}
 		return rbxfile.ValueSharedString(b)
+	case rbxfile.TypeContentData:
+		var value rbxfile.ValueContentData
+		var data string
+		if !indexJSON(ivalue, "hash", &value.Hash) ||
+			!indexJSON(ivalue, "data", &data) {
+			return nil
+		}
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil
+		}
+		if len(b) > 0 {
+			value.Data = b
+		}
+		return value
 	}
 	return nil
 }
@@ -0,0 +1,120 @@
+package rbxfile
+
+import (
+	"strconv"
+)
+
+// Limits specifies bounds on the resources used while decoding. It is meant
+// to be used when decoding untrusted data, where a small, crafted file could
+// otherwise cause a decoder to allocate an arbitrary amount of memory.
+//
+// A decoder that honors Limits returns an ErrLimit when a limit is exceeded.
+// A field with a value of zero or less indicates that the corresponding
+// resource is not limited. The zero value of Limits therefore places no
+// limits on decoding.
+type Limits struct {
+	// MaxChunkSize is the maximum size of a single chunk, in bytes, after
+	// decompression. Only applies to formats that are divided into chunks.
+	MaxChunkSize int64
+
+	// MaxTotalBytes is the maximum number of bytes that are processed. For
+	// formats that compress data, this counts the size of the data after
+	// decompression.
+	MaxTotalBytes int64
+
+	// MaxInstances is the maximum number of instances.
+	MaxInstances int
+
+	// MaxProperties is the maximum number of properties, counted across all
+	// instances.
+	MaxProperties int
+
+	// MaxStringLength is the maximum length of a single string, in bytes.
+	// This includes string values, as well as names of classes and
+	// properties.
+	MaxStringLength int
+
+	// MaxDepth is the maximum depth of the instance tree, where top-level
+	// instances have a depth of 1.
+	//
+	// For formats that represent instances as nested elements, such as XML,
+	// the limit applies to the nesting of elements instead. Because such
+	// formats nest additional elements within each instance, the maximum
+	// depth of the instance tree will be somewhat lower.
+	MaxDepth int
+}
+
+// Limit indicates one of the fields of Limits.
+type Limit uint8
+
+const (
+	LimitChunkSize    Limit = iota + 1 // Limits.MaxChunkSize
+	LimitTotalBytes                    // Limits.MaxTotalBytes
+	LimitInstances                     // Limits.MaxInstances
+	LimitProperties                    // Limits.MaxProperties
+	LimitStringLength                  // Limits.MaxStringLength
+	LimitDepth                         // Limits.MaxDepth
+)
+
+var limitStrings = map[Limit]string{
+	LimitChunkSize:    "chunk size",
+	LimitTotalBytes:   "total bytes",
+	LimitInstances:    "instance count",
+	LimitProperties:   "property count",
+	LimitStringLength: "string length",
+	LimitDepth:        "tree depth",
+}
+
+// String returns a string representation of the limit.
+func (l Limit) String() string {
+	s, ok := limitStrings[l]
+	if !ok {
+		return "invalid limit"
+	}
+	return s
+}
+
+// Max returns the value of the field of Limits indicated by limit.
+func (l Limits) Max(limit Limit) int64 {
+	switch limit {
+	case LimitChunkSize:
+		return l.MaxChunkSize
+	case LimitTotalBytes:
+		return l.MaxTotalBytes
+	case LimitInstances:
+		return int64(l.MaxInstances)
+	case LimitProperties:
+		return int64(l.MaxProperties)
+	case LimitStringLength:
+		return int64(l.MaxStringLength)
+	case LimitDepth:
+		return int64(l.MaxDepth)
+	}
+	return 0
+}
+
+// Check returns an ErrLimit if n exceeds the given limit. Returns nil
+// otherwise, or if the limit is not set.
+func (l Limits) Check(limit Limit, n int64) error {
+	if max := l.Max(limit); max > 0 && n > max {
+		return ErrLimit{Limit: limit, Max: max, Value: n}
+	}
+	return nil
+}
+
+// ErrLimit is returned by a decoder when data exceeds one of the Limits.
+type ErrLimit struct {
+	// Limit indicates the limit that was exceeded.
+	Limit Limit
+
+	// Max is the value of the limit.
+	Max int64
+
+	// Value is the amount that exceeded the limit.
+	Value int64
+}
+
+func (err ErrLimit) Error() string {
+	return "exceeded " + err.Limit.String() + " limit: " +
+		strconv.FormatInt(err.Value, 10) + " > " + strconv.FormatInt(err.Max, 10)
+}
@@ -0,0 +1,380 @@
+// The lua package is used to export rbxfile values and instances as Lua
+// source code, which can be pasted into scripts.
+package lua
+
+import (
+	"fmt"
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxfile"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValueToLua returns a Lua expression that constructs v, such as
+// `Vector3.new(1, 2, 3)`. Token values are written as numbers, and Reference
+// values are written as nil. A ContentData is written as a string containing
+// its hash, since Lua has no representation of its data. Returns "nil" if v is
+// nil or of an unknown type.
+func ValueToLua(v rbxfile.Value) string {
+	return Exporter{}.ValueToLua(v)
+}
+
+// InstanceToLua returns a Lua script that rebuilds inst and its descendants,
+// and returns the rebuilt inst. See Exporter.InstanceToLua for details.
+func InstanceToLua(inst *rbxfile.Instance) string {
+	return Exporter{}.InstanceToLua(inst)
+}
+
+// Exporter configures how values and instances are exported.
+type Exporter struct {
+	// API is used to determine the enum of Token properties. If not nil, then
+	// the value of a Token property is written as an enum item, such as
+	// `Enum.Material.Plastic`.
+	API rbxapi.Root
+}
+
+// ValueToLua returns a Lua expression that constructs v, as with the
+// ValueToLua function.
+func (e Exporter) ValueToLua(v rbxfile.Value) string {
+	var b strings.Builder
+	writeValue(&b, v)
+	return b.String()
+}
+
+// PropertyToLua returns a Lua expression that constructs v, which is the
+// value of the given property of an instance of the given class. If v is a
+// Token, and the API describes the property as an enum with an item of the
+// same value, then the item is written. Otherwise, the result is the same as
+// ValueToLua.
+func (e Exporter) PropertyToLua(className, property string, v rbxfile.Value) string {
+	if token, ok := v.(rbxfile.ValueToken); ok {
+		if item := e.enumItem(className, property, int(token)); item != "" {
+			return item
+		}
+	}
+	return e.ValueToLua(v)
+}
+
+// enumItem returns the enum item of the property that has the given value,
+// or an empty string if the item could not be determined.
+func (e Exporter) enumItem(className, property string, value int) string {
+	if e.API == nil {
+		return ""
+	}
+	for class := e.API.GetClass(className); class != nil; class = e.API.GetClass(class.GetSuperclass()) {
+		prop, ok := class.GetMember(property).(rbxapi.Property)
+		if !ok {
+			continue
+		}
+		enum := e.API.GetEnum(prop.GetValueType().GetName())
+		if enum == nil {
+			return ""
+		}
+		for _, item := range enum.GetEnumItems() {
+			if item.GetValue() == value {
+				return "Enum." + enum.GetName() + "." + item.GetName()
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// InstanceToLua returns a Lua script that rebuilds inst and its descendants,
+// and returns the rebuilt inst. Instances are created with Instance.new and
+// stored in a local table. Each property is set in order of name, after which
+// the instance is parented. Reference properties are set last, once every
+// instance exists. A reference to an instance outside of the tree is written
+// as a comment.
+func (e Exporter) InstanceToLua(inst *rbxfile.Instance) string {
+	var b strings.Builder
+	b.WriteString("local instances = {}\n")
+	if inst == nil {
+		b.WriteString("return nil\n")
+		return b.String()
+	}
+
+	index := map[*rbxfile.Instance]int{}
+	type reference struct {
+		inst     int
+		property string
+		target   *rbxfile.Instance
+	}
+	var refs []reference
+	var write func(inst *rbxfile.Instance, parent int)
+	write = func(inst *rbxfile.Instance, parent int) {
+		i := len(index) + 1
+		index[inst] = i
+		variable := "instances[" + strconv.Itoa(i) + "]"
+		b.WriteString(variable + " = Instance.new(")
+		writeString(&b, inst.ClassName)
+		b.WriteString(")\n")
+
+		var names []string
+		inst.Range(func(name string, value rbxfile.Value) bool {
+			names = append(names, name)
+			return true
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			value := inst.Get(name)
+			if ref, ok := value.(rbxfile.ValueReference); ok {
+				if ref.Instance != nil {
+					refs = append(refs, reference{inst: i, property: name, target: ref.Instance})
+				}
+				continue
+			}
+			b.WriteString(variable + propertyKey(name) + " = ")
+			b.WriteString(e.PropertyToLua(inst.ClassName, name, value))
+			b.WriteString("\n")
+		}
+		if parent > 0 {
+			b.WriteString(variable + ".Parent = instances[" + strconv.Itoa(parent) + "]\n")
+		}
+		for _, child := range inst.Children {
+			write(child, i)
+		}
+	}
+	write(inst, 0)
+
+	for _, ref := range refs {
+		target, ok := index[ref.target]
+		if !ok {
+			b.WriteString("-- ")
+		}
+		b.WriteString("instances[" + strconv.Itoa(ref.inst) + "]" + propertyKey(ref.property))
+		if ok {
+			b.WriteString(" = instances[" + strconv.Itoa(target) + "]\n")
+		} else {
+			b.WriteString(" refers to an instance outside of the tree\n")
+		}
+	}
+	b.WriteString("return instances[1]\n")
+	return b.String()
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "continue": true, "do": true, "else": true,
+	"elseif": true, "end": true, "false": true, "for": true, "function": true,
+	"if": true, "in": true, "local": true, "nil": true, "not": true, "or": true,
+	"repeat": true, "return": true, "then": true, "true": true, "until": true,
+	"while": true,
+}
+
+// propertyKey returns the expression that indexes a property, either as
+// `.Name` or `["Name"]`.
+func propertyKey(name string) string {
+	if isIdentifier(name) {
+		return "." + name
+	}
+	var b strings.Builder
+	b.WriteString("[")
+	writeString(&b, name)
+	b.WriteString("]")
+	return b.String()
+}
+
+// isIdentifier returns whether s is a valid Lua identifier.
+func isIdentifier(s string) bool {
+	if s == "" || keywords[s] {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeString writes s as a quoted Lua string.
+func writeString(b *strings.Builder, s string) {
+	valid := utf8.ValidString(s)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7F || c >= 0x80 && !valid {
+				// Always three digits, so that a following digit is not
+				// included in the escape.
+				fmt.Fprintf(b, "\\%03d", c)
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// number returns f as a Lua number.
+func number(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "math.huge"
+	case math.IsInf(f, -1):
+		return "-math.huge"
+	case math.IsNaN(f):
+		return "0/0"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// writeCall writes a call to constructor with each of args.
+func writeCall(b *strings.Builder, constructor string, args ...string) {
+	b.WriteString(constructor)
+	b.WriteByte('(')
+	b.WriteString(strings.Join(args, ", "))
+	b.WriteByte(')')
+}
+
+func float32s(f ...float32) []string {
+	s := make([]string, len(f))
+	for i, f := range f {
+		s[i] = number(float64(f), 32)
+	}
+	return s
+}
+
+func ints(n ...int64) []string {
+	s := make([]string, len(n))
+	for i, n := range n {
+		s[i] = strconv.FormatInt(n, 10)
+	}
+	return s
+}
+
+// writeEnums writes a call to constructor with the enum items of each name
+// whose flag is set.
+func writeEnums(b *strings.Builder, constructor, enum string, names []string, flags []bool) {
+	var args []string
+	for i, name := range names {
+		if flags[i] {
+			args = append(args, "Enum."+enum+"."+name)
+		}
+	}
+	writeCall(b, constructor, args...)
+}
+
+func writeValue(b *strings.Builder, v rbxfile.Value) {
+	switch v := v.(type) {
+	case rbxfile.ValueString:
+		writeString(b, string(v))
+	case rbxfile.ValueBinaryString:
+		writeString(b, string(v))
+	case rbxfile.ValueProtectedString:
+		writeString(b, string(v))
+	case rbxfile.ValueContent:
+		writeString(b, string(v))
+	case rbxfile.ValueSharedString:
+		writeString(b, string(v))
+	case rbxfile.ValueContentData:
+		writeString(b, v.Hash)
+	case rbxfile.ValueBool:
+		b.WriteString(v.String())
+	case rbxfile.ValueInt:
+		b.WriteString(v.String())
+	case rbxfile.ValueInt64:
+		b.WriteString(v.String())
+	case rbxfile.ValueToken:
+		b.WriteString(v.String())
+	case rbxfile.ValueFloat:
+		b.WriteString(number(float64(v), 32))
+	case rbxfile.ValueDouble:
+		b.WriteString(number(float64(v), 64))
+	case rbxfile.ValueUDim:
+		writeCall(b, "UDim.new", number(float64(v.Scale), 32), strconv.FormatInt(int64(v.Offset), 10))
+	case rbxfile.ValueUDim2:
+		writeCall(b, "UDim2.new",
+			number(float64(v.X.Scale), 32), strconv.FormatInt(int64(v.X.Offset), 10),
+			number(float64(v.Y.Scale), 32), strconv.FormatInt(int64(v.Y.Offset), 10),
+		)
+	case rbxfile.ValueRay:
+		b.WriteString("Ray.new(")
+		writeValue(b, v.Origin)
+		b.WriteString(", ")
+		writeValue(b, v.Direction)
+		b.WriteString(")")
+	case rbxfile.ValueFaces:
+		writeEnums(b, "Faces.new", "NormalId",
+			[]string{"Right", "Top", "Back", "Left", "Bottom", "Front"},
+			[]bool{v.Right, v.Top, v.Back, v.Left, v.Bottom, v.Front},
+		)
+	case rbxfile.ValueAxes:
+		writeEnums(b, "Axes.new", "Axis",
+			[]string{"X", "Y", "Z"},
+			[]bool{v.X, v.Y, v.Z},
+		)
+	case rbxfile.ValueBrickColor:
+		writeCall(b, "BrickColor.new", v.String())
+	case rbxfile.ValueColor3:
+		writeCall(b, "Color3.new", float32s(v.R, v.G, v.B)...)
+	case rbxfile.ValueColor3uint8:
+		writeCall(b, "Color3.fromRGB", ints(int64(v.R), int64(v.G), int64(v.B))...)
+	case rbxfile.ValueVector2:
+		writeCall(b, "Vector2.new", float32s(v.X, v.Y)...)
+	case rbxfile.ValueVector3:
+		writeCall(b, "Vector3.new", float32s(v.X, v.Y, v.Z)...)
+	case rbxfile.ValueVector2int16:
+		writeCall(b, "Vector2int16.new", ints(int64(v.X), int64(v.Y))...)
+	case rbxfile.ValueVector3int16:
+		writeCall(b, "Vector3int16.new", ints(int64(v.X), int64(v.Y), int64(v.Z))...)
+	case rbxfile.ValueCFrame:
+		args := float32s(v.Position.X, v.Position.Y, v.Position.Z)
+		args = append(args, float32s(v.Rotation[:]...)...)
+		writeCall(b, "CFrame.new", args...)
+	case rbxfile.ValueReference:
+		b.WriteString("nil")
+	case rbxfile.ValueNumberSequence:
+		b.WriteString("NumberSequence.new{")
+		for i, k := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeCall(b, "NumberSequenceKeypoint.new", float32s(k.Time, k.Value, k.Envelope)...)
+		}
+		b.WriteString("}")
+	case rbxfile.ValueColorSequence:
+		// ColorSequenceKeypoint has no envelope.
+		b.WriteString("ColorSequence.new{")
+		for i, k := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("ColorSequenceKeypoint.new(")
+			b.WriteString(number(float64(k.Time), 32))
+			b.WriteString(", ")
+			writeValue(b, k.Value)
+			b.WriteString(")")
+		}
+		b.WriteString("}")
+	case rbxfile.ValueNumberRange:
+		writeCall(b, "NumberRange.new", float32s(v.Min, v.Max)...)
+	case rbxfile.ValueRect2D:
+		writeCall(b, "Rect.new", float32s(v.Min.X, v.Min.Y, v.Max.X, v.Max.Y)...)
+	case rbxfile.ValuePhysicalProperties:
+		if !v.CustomPhysics {
+			b.WriteString("nil")
+			break
+		}
+		writeCall(b, "PhysicalProperties.new", float32s(v.Density, v.Friction, v.Elasticity, v.FrictionWeight, v.ElasticityWeight)...)
+	default:
+		b.WriteString("nil")
+	}
+}
@@ -0,0 +1,132 @@
+package lua
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxapi"
+	"github.com/robloxapi/rbxapi/rbxapijson"
+	"github.com/robloxapi/rbxfile"
+)
+
+func TestValueToLua(t *testing.T) {
+	tests := []struct {
+		value rbxfile.Value
+		lua   string
+	}{
+		{nil, "nil"},
+		{rbxfile.ValueString("a\"b\\c\nd\x00" + "1"), `"a\"b\\c\nd\0001"`},
+		{rbxfile.ValueBinaryString("\xFF"), `"\255"`},
+		{rbxfile.ValueString("héllo"), `"héllo"`},
+		{rbxfile.ValueBool(true), "true"},
+		{rbxfile.ValueInt(-5), "-5"},
+		{rbxfile.ValueFloat(0.1), "0.1"},
+		{rbxfile.ValueDouble(math.Inf(-1)), "-math.huge"},
+		{rbxfile.ValueDouble(math.NaN()), "0/0"},
+		{rbxfile.ValueDouble(1e21), "1e+21"},
+		{rbxfile.ValueUDim{Scale: 0.5, Offset: 10}, "UDim.new(0.5, 10)"},
+		{rbxfile.ValueUDim2{X: rbxfile.ValueUDim{Scale: 0.5, Offset: 10}}, "UDim2.new(0.5, 10, 0, 0)"},
+		{rbxfile.ValueRay{Direction: rbxfile.ValueVector3{Y: -1}}, "Ray.new(Vector3.new(0, 0, 0), Vector3.new(0, -1, 0))"},
+		{rbxfile.ValueFaces{Top: true, Front: true}, "Faces.new(Enum.NormalId.Top, Enum.NormalId.Front)"},
+		{rbxfile.ValueAxes{}, "Axes.new()"},
+		{rbxfile.ValueBrickColor(194), "BrickColor.new(194)"},
+		{rbxfile.ValueColor3{R: 1, G: 0.5}, "Color3.new(1, 0.5, 0)"},
+		{rbxfile.ValueColor3uint8{R: 255}, "Color3.fromRGB(255, 0, 0)"},
+		{rbxfile.ValueVector2{X: 1, Y: 2}, "Vector2.new(1, 2)"},
+		{rbxfile.ValueVector3{X: 1, Y: 2, Z: 3}, "Vector3.new(1, 2, 3)"},
+		{rbxfile.ValueVector2int16{X: 1, Y: -2}, "Vector2int16.new(1, -2)"},
+		{rbxfile.ValueVector3int16{X: 1, Y: 2, Z: 3}, "Vector3int16.new(1, 2, 3)"},
+		{rbxfile.ValueCFrame{
+			Position: rbxfile.ValueVector3{X: 1, Y: 2, Z: 3},
+			Rotation: [9]float32{1, 0, 0, 0, 1, 0, 0, 0, 1},
+		}, "CFrame.new(1, 2, 3, 1, 0, 0, 0, 1, 0, 0, 0, 1)"},
+		{rbxfile.ValueToken(3), "3"},
+		{rbxfile.ValueReference{}, "nil"},
+		{rbxfile.ValueNumberSequence{{Time: 0, Value: 1}, {Time: 1, Value: 0, Envelope: 0.5}},
+			"NumberSequence.new{NumberSequenceKeypoint.new(0, 1, 0), NumberSequenceKeypoint.new(1, 0, 0.5)}"},
+		{rbxfile.ValueColorSequence{{Time: 0, Value: rbxfile.ValueColor3{R: 1}}, {Time: 1, Value: rbxfile.ValueColor3{B: 1}}},
+			"ColorSequence.new{ColorSequenceKeypoint.new(0, Color3.new(1, 0, 0)), ColorSequenceKeypoint.new(1, Color3.new(0, 0, 1))}"},
+		{rbxfile.ValueNumberRange{Min: -1, Max: 1}, "NumberRange.new(-1, 1)"},
+		{rbxfile.ValueRect2D{Max: rbxfile.ValueVector2{X: 2, Y: 3}}, "Rect.new(0, 0, 2, 3)"},
+		{rbxfile.ValuePhysicalProperties{}, "nil"},
+		{rbxfile.ValuePhysicalProperties{CustomPhysics: true, Density: 0.7, Friction: 0.3, Elasticity: 0.5, FrictionWeight: 1, ElasticityWeight: 1},
+			"PhysicalProperties.new(0.7, 0.3, 0.5, 1, 1)"},
+		{rbxfile.ValueInt64(-9007199254740993), "-9007199254740993"},
+		{rbxfile.ValueContentData{Hash: "abc"}, `"abc"`},
+	}
+	for _, test := range tests {
+		if lua := ValueToLua(test.value); lua != test.lua {
+			t.Errorf("%#v: got %s, expected %s", test.value, lua, test.lua)
+		}
+	}
+}
+
+func testAPI() *rbxapijson.Root {
+	return &rbxapijson.Root{
+		Classes: []*rbxapijson.Class{
+			{Name: "Instance"},
+			{Name: "BasePart", Superclass: "Instance", Members: []rbxapi.Member{
+				&rbxapijson.Property{Name: "Material", ValueType: rbxapijson.Type{Category: "Enum", Name: "Material"}},
+			}},
+			{Name: "Part", Superclass: "BasePart"},
+		},
+		Enums: []*rbxapijson.Enum{
+			{Name: "Material", Items: []*rbxapijson.EnumItem{
+				{Name: "Plastic", Value: 256},
+				{Name: "Wood", Value: 512},
+			}},
+		},
+	}
+}
+
+func TestPropertyToLua(t *testing.T) {
+	e := Exporter{API: testAPI()}
+	if lua := e.PropertyToLua("Part", "Material", rbxfile.ValueToken(512)); lua != "Enum.Material.Wood" {
+		t.Errorf("unexpected enum item %s", lua)
+	}
+	if lua := e.PropertyToLua("Part", "Material", rbxfile.ValueToken(1)); lua != "1" {
+		t.Errorf("unexpected unknown item %s", lua)
+	}
+	if lua := e.PropertyToLua("Part", "Shape", rbxfile.ValueToken(1)); lua != "1" {
+		t.Errorf("unexpected unknown property %s", lua)
+	}
+	if lua := (Exporter{}).PropertyToLua("Part", "Material", rbxfile.ValueToken(512)); lua != "512" {
+		t.Errorf("unexpected token without API %s", lua)
+	}
+}
+
+func TestInstanceToLua(t *testing.T) {
+	model := rbxfile.NewInstance("Model", nil)
+	model.SetName("Model")
+	part := rbxfile.NewInstance("Part", model)
+	part.SetName("Part")
+	part.Set("Material", rbxfile.ValueToken(256))
+	part.Set("Size", rbxfile.ValueVector3{X: 4, Y: 1, Z: 2})
+	part.Set("size xml", rbxfile.ValueVector3{X: 4, Y: 1, Z: 2})
+	model.Set("PrimaryPart", rbxfile.ValueReference{Instance: part})
+	part.Set("Outside", rbxfile.ValueReference{Instance: rbxfile.NewInstance("Folder", nil)})
+	part.Set("Empty", rbxfile.ValueReference{})
+
+	expected := strings.Join([]string{
+		`local instances = {}`,
+		`instances[1] = Instance.new("Model")`,
+		`instances[1].Name = "Model"`,
+		`instances[2] = Instance.new("Part")`,
+		`instances[2].Material = Enum.Material.Plastic`,
+		`instances[2].Name = "Part"`,
+		`instances[2].Size = Vector3.new(4, 1, 2)`,
+		`instances[2]["size xml"] = Vector3.new(4, 1, 2)`,
+		`instances[2].Parent = instances[1]`,
+		`instances[1].PrimaryPart = instances[2]`,
+		`-- instances[2].Outside refers to an instance outside of the tree`,
+		`return instances[1]`,
+		``,
+	}, "\n")
+	if lua := (Exporter{API: testAPI()}).InstanceToLua(model); lua != expected {
+		t.Errorf("unexpected script:\n%s\nexpected:\n%s", lua, expected)
+	}
+	if lua := InstanceToLua(nil); lua != "local instances = {}\nreturn nil\n" {
+		t.Errorf("unexpected script for nil instance:\n%s", lua)
+	}
+}
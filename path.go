@@ -0,0 +1,221 @@
+package rbxfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PathElement is one element of a path, which selects a child by name.
+type PathElement struct {
+	// Name is the name of the child.
+	Name string
+
+	// Index selects among siblings that have the same name, starting at 1.
+	// An Index of 0 is the same as 1.
+	Index int
+}
+
+// ParsePath parses a path, which addresses an instance by the names of its
+// ancestors. A path is a sequence of elements, each of which selects a child
+// by name. Elements are written in one of two forms:
+//
+//	.Name
+//	["Name"]
+//
+// The first form may contain any characters other than `.`, `[`, `]` and `"`,
+// and the `.` is omitted for the first element of the path. The second form is
+// a quoted string, as accepted by strconv.Unquote, and may contain any name.
+// Either form may be followed by an index in brackets, which selects among
+// siblings with the same name, counting from 1. For example:
+//
+//	Workspace.Map["Door.1"][2]
+//
+// selects the second child named "Door.1" of the Map in the Workspace.
+func ParsePath(path string) (elements []PathElement, err error) {
+	if path == "" {
+		return nil, fmt.Errorf("path: empty path")
+	}
+	i := 0
+	for i < len(path) {
+		var elem PathElement
+		switch {
+		case strings.HasPrefix(path[i:], `["`):
+			end := quotedEnd(path, i+1)
+			if end < 0 || end >= len(path) || path[end] != ']' {
+				return nil, fmt.Errorf("path: unterminated name at offset %d", i)
+			}
+			if elem.Name, err = strconv.Unquote(path[i+1 : end]); err != nil {
+				return nil, fmt.Errorf("path: invalid name at offset %d: %w", i, err)
+			}
+			i = end + 1
+		case path[i] == '.' && i > 0, path[i] != '.' && i == 0:
+			if path[i] == '.' {
+				i++
+			}
+			j := i
+			for j < len(path) && !strings.ContainsRune(`.[]"`, rune(path[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("path: expected name at offset %d", i)
+			}
+			elem.Name = path[i:j]
+			i = j
+		default:
+			return nil, fmt.Errorf("path: unexpected %q at offset %d", path[i], i)
+		}
+
+		if i < len(path) && path[i] == '[' && !strings.HasPrefix(path[i:], `["`) {
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path: unterminated index at offset %d", i)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 1 {
+				return nil, fmt.Errorf("path: invalid index at offset %d", i)
+			}
+			elem.Index = index
+			i += end + 1
+		}
+		elements = append(elements, elem)
+	}
+	return elements, nil
+}
+
+// quotedEnd returns the offset following the quoted string that begins at
+// offset i of s, or -1 if the string is not terminated.
+func quotedEnd(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// FormatPath returns the path of a list of elements, as parsed by ParsePath.
+// A name is written in the quoted form unless it consists only of letters,
+// digits and underscores. An index is written only when greater than 1.
+func FormatPath(elements []PathElement) string {
+	var b strings.Builder
+	for i, elem := range elements {
+		if plainName(elem.Name) {
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(elem.Name)
+		} else {
+			b.WriteByte('[')
+			b.WriteString(strconv.Quote(elem.Name))
+			b.WriteByte(']')
+		}
+		if elem.Index > 1 {
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(elem.Index))
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// plainName returns whether a name can be written without quotes.
+func plainName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// findElement returns the instance in list selected by elem, or nil if no
+// such instance exists.
+func findElement(list []*Instance, elem PathElement) *Instance {
+	n := 0
+	for _, inst := range list {
+		if inst.Name() != elem.Name {
+			continue
+		}
+		if n++; n >= elem.Index {
+			return inst
+		}
+	}
+	return nil
+}
+
+// findPath returns the instance selected by path, starting with the
+// instances in list.
+func findPath(list []*Instance, path string) (*Instance, error) {
+	elements, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var inst *Instance
+	for _, elem := range elements {
+		if inst = findElement(list, elem); inst == nil {
+			return nil, nil
+		}
+		list = inst.Children
+	}
+	return inst, nil
+}
+
+// FindByPath returns the instance selected by path, as parsed by ParsePath,
+// where the first element selects one of the root instances. Returns nil if
+// no instance was found, and an error if the path is malformed.
+func (root *Root) FindByPath(path string) (*Instance, error) {
+	return findPath(root.Instances, path)
+}
+
+// FindByPath returns the descendant selected by path, as parsed by ParsePath,
+// where the first element selects a child of the instance. Returns nil if no
+// instance was found, and an error if the path is malformed.
+func (inst *Instance) FindByPath(path string) (*Instance, error) {
+	return findPath(inst.Children, path)
+}
+
+// PathFrom returns the path that selects the instance from ancestor, such
+// that ancestor.FindByPath returns the instance. If ancestor is nil or is not
+// an ancestor of the instance, then the path begins with the top-level
+// ancestor of the instance, as used by Root.FindByPath. Returns an empty
+// string if the instance is ancestor.
+//
+// The index of a top-level instance is determined by the Root that owns it.
+// If the instance is not owned by a Root, then the first element of the path
+// has no index.
+func (inst *Instance) PathFrom(ancestor *Instance) string {
+	if inst == ancestor {
+		return ""
+	}
+	var elements []PathElement
+	for object := inst; object != nil && object != ancestor; object = object.Parent() {
+		elem := PathElement{Name: object.Name(), Index: 1}
+		var siblings []*Instance
+		if parent := object.Parent(); parent != nil {
+			siblings = parent.Children
+		} else if object.root != nil {
+			siblings = object.root.Instances
+		}
+		for _, sibling := range siblings {
+			if sibling == object {
+				break
+			}
+			if sibling.Name() == elem.Name {
+				elem.Index++
+			}
+		}
+		elements = append(elements, elem)
+	}
+	for i, j := 0, len(elements)-1; i < j; i, j = i+1, j-1 {
+		elements[i], elements[j] = elements[j], elements[i]
+	}
+	return FormatPath(elements)
+}
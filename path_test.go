@@ -0,0 +1,107 @@
+package rbxfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		elements []PathElement
+	}{
+		{`Workspace`, []PathElement{{Name: "Workspace"}}},
+		{`Workspace.Map["Door.1"][2]`, []PathElement{{Name: "Workspace"}, {Name: "Map"}, {Name: "Door.1", Index: 2}}},
+		{`["a\"b"].c d[3].e`, []PathElement{{Name: `a"b`}, {Name: "c d", Index: 3}, {Name: "e"}}},
+		{`[""]`, []PathElement{{Name: ""}}},
+	}
+	for _, test := range tests {
+		elements, err := ParsePath(test.path)
+		if err != nil {
+			t.Errorf("%s: %s", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(elements, test.elements) {
+			t.Errorf("%s: expected %v, got %v", test.path, test.elements, elements)
+		}
+	}
+
+	for _, path := range []string{``, `.A`, `A.`, `A..B`, `A[0]`, `A[x]`, `A["B"`, `A["B]`, `A]`, `A["B"]C`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Errorf("%s: expected error", path)
+		}
+	}
+
+	elements := []PathElement{{Name: "Workspace"}, {Name: "Door.1", Index: 2}, {Name: ""}, {Name: "x", Index: 1}}
+	path := FormatPath(elements)
+	if want := `Workspace["Door.1"][2][""].x`; path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+}
+
+func TestFindByPath(t *testing.T) {
+	workspace := NewInstance("Workspace", nil)
+	workspace.SetName("Workspace")
+	doorModel := NewInstance("Model", workspace)
+	doorModel.SetName("Map")
+	var doors []*Instance
+	for i := 0; i < 3; i++ {
+		door := NewInstance("Part", doorModel)
+		door.SetName("Door.1")
+		doors = append(doors, door)
+	}
+	NewInstance("Decal", doors[1]).SetName("Sign")
+	root := &Root{Instances: []*Instance{workspace}}
+
+	inst, err := root.FindByPath(`Workspace.Map["Door.1"][2]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst != doors[1] {
+		t.Errorf("unexpected instance %v", inst)
+	}
+	if inst, _ := root.FindByPath(`Workspace.Map["Door.1"][4]`); inst != nil {
+		t.Errorf("expected no instance, got %v", inst)
+	}
+	if _, err := root.FindByPath(`Workspace..Map`); err == nil {
+		t.Error("expected error")
+	}
+
+	for _, inst := range root.GetDescendants() {
+		path := inst.PathFrom(nil)
+		if found, err := root.FindByPath(path); err != nil || found != inst {
+			t.Errorf("%s: expected path to select instance", path)
+		}
+		if inst == workspace {
+			continue
+		}
+		path = inst.PathFrom(workspace)
+		if found, err := workspace.FindByPath(path); err != nil || found != inst {
+			t.Errorf("%s: expected relative path to select instance", path)
+		}
+	}
+	if path := doors[1].Children[0].PathFrom(doorModel); path != `["Door.1"][2].Sign` {
+		t.Errorf("unexpected path %s", path)
+	}
+	if path := workspace.PathFrom(workspace); path != "" {
+		t.Errorf("expected empty path, got %s", path)
+	}
+
+	// Top-level instances with the same name are indexed within the root.
+	other := NewInstance("Workspace", nil)
+	other.SetName("Workspace")
+	sign := NewInstance("Decal", other)
+	sign.SetName("Sign")
+	if path := sign.PathFrom(nil); path != "Workspace.Sign" {
+		t.Errorf("unexpected path %s without root", path)
+	}
+	if err := root.AddInstance(other); err != nil {
+		t.Fatal(err)
+	}
+	if path := sign.PathFrom(nil); path != "Workspace[2].Sign" {
+		t.Errorf("unexpected path %s", path)
+	}
+	if found, err := root.FindByPath(sign.PathFrom(nil)); err != nil || found != sign {
+		t.Error("expected path to select instance")
+	}
+}
@@ -0,0 +1,19 @@
+package rbxfile
+
+// Progress describes the progress of decoding or encoding a file. Each field
+// is a running total.
+type Progress struct {
+	// Bytes is the number of bytes read or written.
+	Bytes int64
+
+	// Chunks is the number of chunks read or written. Only applies to formats
+	// that are divided into chunks.
+	Chunks int
+
+	// Instances is the number of instances built while decoding.
+	Instances int
+}
+
+// ProgressFunc receives the progress of an operation. It is called
+// synchronously as the operation advances, and so should return quickly.
+type ProgressFunc func(progress Progress)
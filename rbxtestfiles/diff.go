@@ -92,7 +92,7 @@ func setLine(buf []byte, l int, op byte) []byte {
 	return buf
 }
 
-func diffGolden(input string, context int, curr, spec []byte) error {
+func diffGolden(from, to string, context int, curr, spec []byte) error {
 	var icurr, ispec interface{}
 	if but.IfError(json.Unmarshal(curr, &icurr), "skipping comparison: current") {
 		goto lineDiff
@@ -191,10 +191,10 @@ lineDiff:
 
 	var b strings.Builder
 	b.WriteString("--- ")
-	b.WriteString(input)
-	b.WriteString(".golden\n")
+	b.WriteString(from)
+	b.WriteByte('\n')
 	b.WriteString("+++ ")
-	b.WriteString(input)
+	b.WriteString(to)
 	b.WriteByte('\n')
 	var prev lineData
 	for _, line := range lines {
@@ -330,6 +330,7 @@ func (g *Golden) value(v interface{}) {
 		if r, ok := g.refs[v]; ok {
 			ref = r
 		}
+		v.LoadProperties()
 		g.object(object{
 			field{name: "ClassName", value: v.ClassName},
 			field{name: "IsService", value: v.IsService},
@@ -533,6 +534,12 @@ func (g *Golden) value(v interface{}) {
 	case rbxfile.ValueSharedString:
 		g.value(string(v))
 
+	case rbxfile.ValueContentData:
+		g.object(object{
+			field{name: "Hash", value: v.Hash},
+			field{name: "Data", value: v.Data},
+		})
+
 	case *bin.FormatModel:
 		chunks := make(array, len(v.Chunks))
 		for i, chunk := range v.Chunks {
@@ -599,8 +606,9 @@ func (g *Golden) value(v interface{}) {
 
 	case *bin.ChunkProperty:
 		sig := v.Signature()
-		props := make(array, len(v.Properties))
-		for i, prop := range v.Properties {
+		values := v.Values()
+		props := make(array, len(values))
+		for i, prop := range values {
 			props[i] = prop
 		}
 		g.object(object{
@@ -84,6 +84,13 @@ func openInput(input string) {
 	directives := parseDirectives(input, r)
 	format := directives.pairs["format"]
 
+	content, err := ioutil.ReadAll(r)
+	if but.IfError(err, "read input") {
+		return
+	}
+	checkRoundtrip(input, format, directives, content)
+	r = bufio.NewReader(bytes.NewReader(content))
+
 	var data interface{}
 	switch format {
 	case "rbxl":
@@ -140,7 +147,7 @@ func openInput(input string) {
 	if bytes.Equal(current, spec) {
 		return
 	}
-	if but.IfError(diffGolden(input, int(context), spec, current), "diff spec with current") {
+	if but.IfError(diffGolden(gold, input, int(context), spec, current), "diff spec with current") {
 		return
 	}
 
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/anaminus/but"
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/bin"
+	"github.com/robloxapi/rbxfile/xml"
+)
+
+// crossFormats maps each format to the equivalent format of the other codec.
+var crossFormats = map[string]string{
+	"rbxl":  "rbxlx",
+	"rbxlx": "rbxl",
+	"rbxm":  "rbxmx",
+	"rbxmx": "rbxm",
+}
+
+// decodeRoot decodes b as a Root according to format.
+func decodeRoot(format string, b []byte) (*rbxfile.Root, error) {
+	r := bytes.NewReader(b)
+	switch format {
+	case "rbxl":
+		return bin.DeserializePlace(r, nil)
+	case "rbxm":
+		return bin.DeserializeModel(r, nil)
+	case "rbxlx", "rbxmx":
+		return xml.Deserialize(r, nil)
+	}
+	return nil, errors.New("unknown format " + format)
+}
+
+// encodeRoot encodes root according to format.
+func encodeRoot(format string, root *rbxfile.Root) ([]byte, error) {
+	var w bytes.Buffer
+	var err error
+	switch format {
+	case "rbxl":
+		err = bin.SerializePlace(&w, nil, root)
+	case "rbxm":
+		err = bin.SerializeModel(&w, nil, root)
+	case "rbxlx", "rbxmx":
+		err = xml.Serialize(&w, nil, root)
+	default:
+		err = errors.New("unknown format " + format)
+	}
+	return w.Bytes(), err
+}
+
+// convertRoot encodes root through each of the given formats in order,
+// decoding the result after each step.
+func convertRoot(root *rbxfile.Root, formats ...string) (*rbxfile.Root, error) {
+	for _, format := range formats {
+		b, err := encodeRoot(format, root)
+		if err != nil {
+			return nil, errors.New("encode " + format + ": " + err.Error())
+		}
+		if root, err = decodeRoot(format, b); err != nil {
+			return nil, errors.New("decode " + format + ": " + err.Error())
+		}
+	}
+	return root, nil
+}
+
+// compareRoots prints a diff between the golden representations of want and
+// got. Because references are written as indices, referents that differ
+// between the two roots are not considered.
+func compareRoots(format, from, to string, want, got *rbxfile.Root) {
+	gw := &Golden{}
+	gw.Format(format, want)
+	gg := &Golden{}
+	gg.Format(format, got)
+	spec, current := gw.Bytes(), gg.Bytes()
+	if bytes.Equal(spec, current) {
+		return
+	}
+	but.IfError(diffGolden(from, to, int(context), spec, current), "diff "+from+" with "+to)
+}
+
+// checkRoundtrip handles the roundtrip and crossformat directives, which
+// verify that a decoded root survives being encoded again.
+//
+// The roundtrip directive encodes the root with the codec it was decoded
+// with, then decodes it again. The crossformat directive encodes the root with
+// the codec of the other format (e.g. rbxl to rbxlx), then back to the
+// original format. In both cases, each decoded root is expected to be
+// equivalent to the original.
+func checkRoundtrip(input, format string, d *directives, content []byte) {
+	if !d.flags["roundtrip"] && !d.flags["crossformat"] {
+		return
+	}
+	root, err := decodeRoot(format, content)
+	if but.IfError(err, "roundtrip "+input) {
+		return
+	}
+
+	if d.flags["roundtrip"] {
+		got, err := convertRoot(root, format)
+		if !but.IfError(err, "roundtrip "+input) {
+			compareRoots(format, input, input+" (roundtrip)", root, got)
+		}
+	}
+
+	if d.flags["crossformat"] {
+		cross, ok := crossFormats[format]
+		if !ok {
+			return
+		}
+		got, err := convertRoot(root, cross)
+		if but.IfError(err, "crossformat "+input) {
+			return
+		}
+		compareRoots(format, input, input+" ("+cross+")", root, got)
+		got, err = convertRoot(got, format)
+		if !but.IfError(err, "crossformat "+input) {
+			compareRoots(format, input, input+" ("+cross+" -> "+format+")", root, got)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package rbxfile
 import (
 	"crypto/rand"
 	"io"
+	mrand "math/rand"
+	"strconv"
+	"sync"
 )
 
 // PropRef specifies the property of an instance that is a reference, which is
@@ -38,6 +41,12 @@ func (refs References) Resolve(propRef PropRef) bool {
 // a new reference is generated and applied to the instance. The instance's
 // reference is then added to References.
 func (refs References) Get(instance *Instance) (ref string) {
+	return refs.GetWith(instance, nil)
+}
+
+// GetWith is like Get, but uses gen to generate new references. If gen is
+// nil, then CryptoReferences is used.
+func (refs References) GetWith(instance *Instance, gen ReferenceGenerator) (ref string) {
 	if instance == nil {
 		return ""
 	}
@@ -57,7 +66,7 @@ func (refs References) Get(instance *Instance) (ref string) {
 			// may not match Roblox's implementation. It is difficult to
 			// discern whether this is correct because it is extremely
 			// unlikely that a duplicate will be generated.
-			ref = GenerateReference()
+			ref = generateReference(gen)
 			if _, ok := refs[ref]; !ok {
 				instance.Reference = ref
 				break
@@ -85,6 +94,12 @@ func generateUUID() string {
 	if _, err := io.ReadFull(rand.Reader, buf[:16]); err != nil {
 		panic(err)
 	}
+	return formatUUID(buf)
+}
+
+// formatUUID formats the first 16 bytes of buf as a version 4 UUID, using the
+// remainder of buf as space.
+func formatUUID(buf [32]byte) string {
 	buf[6] = (buf[6] & 0x0F) | 0x40 // Version 4       ; 0100XXXX
 	buf[8] = (buf[8] & 0x3F) | 0x80 // Variant RFC4122 ; 10XXXXXX
 	const hextable = "0123456789ABCDEF"
@@ -96,7 +111,116 @@ func generateUUID() string {
 }
 
 // GenerateReference generates a unique string that can be used as a reference
-// to an Instance.
+// to an Instance. The string is generated by CryptoReferences.
 func GenerateReference() string {
 	return "RBX" + generateUUID()
 }
+
+// ReferenceGenerator generates strings to be used as references to
+// Instances.
+type ReferenceGenerator interface {
+	// GenerateReference returns a new reference. Each reference returned
+	// should be unique.
+	GenerateReference() string
+}
+
+// generateReference generates a reference with gen, or with
+// GenerateReference if gen is nil.
+func generateReference(gen ReferenceGenerator) string {
+	if gen == nil {
+		return GenerateReference()
+	}
+	return gen.GenerateReference()
+}
+
+// CryptoReferences is a ReferenceGenerator that generates references from a
+// cryptographically secure source of random numbers. This is the default
+// generator, which produces different references on every run.
+type CryptoReferences struct{}
+
+// GenerateReference implements ReferenceGenerator.
+func (CryptoReferences) GenerateReference() string {
+	return GenerateReference()
+}
+
+// SeededReferences is a ReferenceGenerator that generates references from a
+// pseudo-random source with a given seed. References have the same form as
+// those of CryptoReferences, but the same seed always produces the same
+// sequence of references.
+//
+// SeededReferences is safe for concurrent use, though references will be
+// reproduced only if they are generated in the same order.
+type SeededReferences struct {
+	mu  sync.Mutex
+	src *mrand.Rand
+}
+
+// NewSeededReferences returns a SeededReferences that is initialized with
+// seed.
+func NewSeededReferences(seed int64) *SeededReferences {
+	return &SeededReferences{src: mrand.New(mrand.NewSource(seed))}
+}
+
+// GenerateReference implements ReferenceGenerator.
+func (g *SeededReferences) GenerateReference() string {
+	var buf [32]byte
+	g.mu.Lock()
+	g.src.Read(buf[:16])
+	g.mu.Unlock()
+	return "RBX" + formatUUID(buf)
+}
+
+// SequentialReferences is a ReferenceGenerator that generates references by
+// appending an incrementing number to a prefix. If Prefix is empty, then
+// "RBX" is used, producing references such as "RBX0", "RBX1", and so on.
+//
+// SequentialReferences is safe for concurrent use.
+type SequentialReferences struct {
+	// Prefix is the string that precedes each number.
+	Prefix string
+
+	// Next is the number to be used by the next reference.
+	Next uint64
+
+	mu sync.Mutex
+}
+
+// GenerateReference implements ReferenceGenerator.
+func (g *SequentialReferences) GenerateReference() string {
+	g.mu.Lock()
+	n := g.Next
+	g.Next++
+	g.mu.Unlock()
+	prefix := g.Prefix
+	if prefix == "" {
+		prefix = "RBX"
+	}
+	return prefix + strconv.FormatUint(n, 10)
+}
+
+// CanonicalizeReferences sets the reference of each instance in root to a
+// sequential reference, numbered in tree order, such that a tree with the
+// same structure always has the same references. If root.ReferenceGenerator
+// is a *SequentialReferences, then its Prefix is used.
+//
+// Because property values refer to instances directly, references between
+// instances are not affected. The reference index of root is rebuilt to use
+// the new references.
+func CanonicalizeReferences(root *Root) {
+	if root == nil {
+		return
+	}
+	gen := &SequentialReferences{}
+	if seq, ok := root.ReferenceGenerator.(*SequentialReferences); ok {
+		gen.Prefix = seq.Prefix
+	}
+	var walk func(instances []*Instance)
+	walk = func(instances []*Instance) {
+		for _, inst := range instances {
+			inst.Reference = gen.GenerateReference()
+			walk(inst.Children)
+		}
+	}
+	walk(root.Instances)
+	root.refs = nil
+}
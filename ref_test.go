@@ -0,0 +1,121 @@
+package rbxfile
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReferenceGenerators(t *testing.T) {
+	uuid := regexp.MustCompile(`^RBX[0-9A-F]{32}$`)
+	if ref := (CryptoReferences{}).GenerateReference(); !uuid.MatchString(ref) {
+		t.Errorf("unexpected crypto reference %q", ref)
+	}
+
+	a, b := NewSeededReferences(42), NewSeededReferences(42)
+	for i := 0; i < 4; i++ {
+		ra, rb := a.GenerateReference(), b.GenerateReference()
+		if ra != rb {
+			t.Errorf("seeded references differ: %q, %q", ra, rb)
+		}
+		if !uuid.MatchString(ra) {
+			t.Errorf("unexpected seeded reference %q", ra)
+		}
+	}
+	if NewSeededReferences(1).GenerateReference() == NewSeededReferences(2).GenerateReference() {
+		t.Error("expected different seeds to produce different references")
+	}
+
+	seq := &SequentialReferences{}
+	for _, want := range []string{"RBX0", "RBX1", "RBX2"} {
+		if ref := seq.GenerateReference(); ref != want {
+			t.Errorf("expected %q, got %q", want, ref)
+		}
+	}
+	seq = &SequentialReferences{Prefix: "ref", Next: 10}
+	if ref := seq.GenerateReference(); ref != "ref10" {
+		t.Errorf("expected %q, got %q", "ref10", ref)
+	}
+}
+
+func TestCanonicalizeReferences(t *testing.T) {
+	build := func() *Root {
+		a := NewInstance("A", nil)
+		b := NewInstance("B", a)
+		NewInstance("C", b)
+		NewInstance("D", a)
+		a.Set("Ref", ValueReference{Instance: b})
+		return &Root{Instances: []*Instance{a, NewInstance("E", nil)}}
+	}
+	root := build()
+	CanonicalizeReferences(root)
+	a := root.Instances[0]
+	refs := []string{
+		a.Reference,
+		a.Children[0].Reference,
+		a.Children[0].Children[0].Reference,
+		a.Children[1].Reference,
+		root.Instances[1].Reference,
+	}
+	for i, ref := range refs {
+		if want := "RBX" + string('0'+rune(i)); ref != want {
+			t.Errorf("instance #%d: expected %q, got %q", i, want, ref)
+		}
+	}
+	if v := a.Get("Ref").(ValueReference); v.Instance != a.Children[0] {
+		t.Error("reference property changed")
+	}
+
+	root = build()
+	root.FixTree()
+	old := root.Instances[1].Reference
+	if root.GetByReference(old) != root.Instances[1] {
+		t.Fatal("expected instance to be indexed")
+	}
+	CanonicalizeReferences(root)
+	if root.GetByReference(old) != nil {
+		t.Error("expected old reference to be removed from index")
+	}
+	if root.GetByReference("RBX4") != root.Instances[1] {
+		t.Error("expected new reference to be indexed")
+	}
+
+	root = build()
+	root.ReferenceGenerator = &SequentialReferences{Prefix: "X"}
+	CanonicalizeReferences(root)
+	if ref := root.Instances[1].Reference; ref != "X4" {
+		t.Errorf("expected prefixed reference, got %q", ref)
+	}
+}
+
+func TestCloneWith(t *testing.T) {
+	inst := NewInstance("Instance", nil)
+	inst.Reference = ""
+	child := NewInstance("Child", inst)
+	child.Reference = ""
+
+	a := inst.CloneWith(NewSeededReferences(1))
+	inst.Reference, child.Reference = "", ""
+	b := inst.CloneWith(NewSeededReferences(1))
+	if a.Reference != b.Reference || a.Children[0].Reference != b.Children[0].Reference {
+		t.Error("expected clones to have the same references")
+	}
+
+	root := &Root{
+		Instances:          []*Instance{NewInstance("Instance", nil)},
+		ReferenceGenerator: &SequentialReferences{},
+	}
+	root.Instances[0].Reference = ""
+	c := root.Copy()
+	if c.Instances[0].Reference != "RBX0" {
+		t.Errorf("unexpected reference %q", c.Instances[0].Reference)
+	}
+	if c.ReferenceGenerator != root.ReferenceGenerator {
+		t.Error("expected generator to be copied")
+	}
+
+	root.FixTree()
+	root.Instances[0].Reference = ""
+	if clone := root.Instances[0].Clone(); clone.Reference != "RBX1" {
+		t.Errorf("expected clone to use generator of root, got %q", clone.Reference)
+	}
+}
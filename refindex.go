@@ -0,0 +1,101 @@
+package rbxfile
+
+import (
+	"fmt"
+)
+
+// GetByReference returns the instance in the tree of the root that has the
+// given reference, or nil if no such instance exists.
+//
+// The root maintains an index of references, which is updated as instances
+// are added to and removed from the tree through methods such as AddChild,
+// SetParent, and AddInstance. If the tree is modified directly, or the
+// Reference field of an instance in the tree is changed, then FixTree must be
+// called to rebuild the index.
+func (root *Root) GetByReference(ref string) *Instance {
+	if ref == "" {
+		return nil
+	}
+	root.buildIndex()
+	inst := root.refs[ref]
+	if inst == nil {
+		return nil
+	}
+	if inst.Reference != ref || inst.Root() != root {
+		// Stale entry; rebuild the index and try again.
+		root.refs = nil
+		root.buildIndex()
+		inst = root.refs[ref]
+	}
+	return inst
+}
+
+// buildIndex builds the reference index of the root, if it has not already
+// been built. When references collide, the first instance in the tree is
+// used.
+func (root *Root) buildIndex() {
+	if root.refs != nil {
+		return
+	}
+	root.refs = References{}
+	root.Walk(func(inst *Instance, depth int) WalkAction {
+		if inst.Reference != "" && root.refs[inst.Reference] == nil {
+			root.refs[inst.Reference] = inst
+		}
+		return WalkContinue
+	}, nil)
+}
+
+// checkReferences returns an error if the reference of inst or one of its
+// descendants is shared with another instance within the tree of inst, or with
+// an instance in the tree of the root other than itself.
+func (root *Root) checkReferences(inst *Instance) error {
+	root.buildIndex()
+	seen := map[string]*Instance{}
+	var err error
+	inst.Walk(func(desc *Instance, depth int) WalkAction {
+		ref := desc.Reference
+		if ref == "" {
+			return WalkContinue
+		}
+		if other := seen[ref]; other != nil {
+			err = fmt.Errorf("reference %s of %s collides with %s", ref, desc.GetFullName(), other.GetFullName())
+			return WalkStop
+		}
+		seen[ref] = desc
+		if other := root.refs[ref]; other != nil && other != desc && other.Reference == ref && other.Root() == root {
+			err = fmt.Errorf("reference %s of %s collides with %s", ref, desc.GetFullName(), other.GetFullName())
+			return WalkStop
+		}
+		return WalkContinue
+	}, nil)
+	return err
+}
+
+// indexTree adds inst and its descendants to the reference index, if the
+// index has been built.
+func (root *Root) indexTree(inst *Instance) {
+	if root.refs == nil {
+		return
+	}
+	inst.Walk(func(desc *Instance, depth int) WalkAction {
+		if desc.Reference != "" {
+			root.refs[desc.Reference] = desc
+		}
+		return WalkContinue
+	}, nil)
+}
+
+// unindexTree removes inst and its descendants from the reference index, if
+// the index has been built.
+func (root *Root) unindexTree(inst *Instance) {
+	if root.refs == nil {
+		return
+	}
+	inst.Walk(func(desc *Instance, depth int) WalkAction {
+		if root.refs[desc.Reference] == desc {
+			delete(root.refs, desc.Reference)
+		}
+		return WalkContinue
+	}, nil)
+}
@@ -0,0 +1,147 @@
+package rbxfile
+
+import (
+	"testing"
+)
+
+func refInst(name, ref string, parent *Instance) *Instance {
+	inst := namedInst(name, parent)
+	inst.Reference = ref
+	return inst
+}
+
+func TestRoot_GetByReference(t *testing.T) {
+	a := refInst("A", "RBXA", nil)
+	b := refInst("B", "RBXB", a)
+	root := &Root{Instances: []*Instance{a}}
+	root.FixTree()
+
+	if root.GetByReference("RBXA") != a || root.GetByReference("RBXB") != b {
+		t.Error("unexpected instance from index")
+	}
+	if root.GetByReference("") != nil || root.GetByReference("RBXC") != nil {
+		t.Error("expected nil instance")
+	}
+
+	c := refInst("C", "RBXC", nil)
+	if err := b.AddChild(c); err != nil {
+		t.Error("failed add child:", err)
+	}
+	if root.GetByReference("RBXC") != c {
+		t.Error("added child not indexed")
+	}
+	d := NewInstance("D", c)
+	if root.GetByReference(d.Reference) != d {
+		t.Error("new instance not indexed")
+	}
+
+	b.SetParent(nil)
+	if root.GetByReference("RBXB") != nil || root.GetByReference("RBXC") != nil {
+		t.Error("removed tree still indexed")
+	}
+	root.AddInstance(b)
+	if root.GetByReference("RBXC") != c {
+		t.Error("added root instance not indexed")
+	}
+	root.RemoveInstance(b)
+	if root.GetByReference("RBXB") != nil {
+		t.Error("removed root instance still indexed")
+	}
+	a.AddChildAt(0, b)
+	if root.GetByReference("RBXB") != b {
+		t.Error("inserted child not indexed")
+	}
+	a.RemoveAll()
+	if root.GetByReference("RBXB") != nil {
+		t.Error("removed children still indexed")
+	}
+
+	a.Reference = "RBXZ"
+	if root.GetByReference("RBXA") != nil {
+		t.Error("stale entry returned")
+	}
+	if root.GetByReference("RBXZ") != a {
+		t.Error("index not rebuilt")
+	}
+}
+
+func TestRoot_ReferenceCollision(t *testing.T) {
+	a := refInst("A", "RBXA", nil)
+	b := refInst("B", "RBXB", a)
+	root := &Root{}
+	if err := root.AddInstance(a); err != nil {
+		t.Fatal("failed add instance:", err)
+	}
+
+	dup := refInst("Dup", "RBXX", nil)
+	refInst("Child", "RBXB", dup)
+	if err := a.AddChild(dup); err == nil {
+		t.Error("no error on colliding child")
+	}
+	if err := a.AddChildAt(0, dup); err == nil {
+		t.Error("no error on colliding child")
+	}
+	if err := dup.SetParent(b); err == nil {
+		t.Error("no error on colliding parent")
+	}
+	if err := root.AddInstance(dup); err == nil {
+		t.Error("no error on colliding root instance")
+	}
+	if dup.Parent() != nil || len(a.Children) != 1 || len(root.Instances) != 1 {
+		t.Error("colliding tree was inserted")
+	}
+
+	self := refInst("Self", "RBXS", nil)
+	refInst("Child", "RBXS", self)
+	if err := a.AddChild(self); err == nil {
+		t.Error("no error on tree colliding with itself")
+	}
+
+	if err := b.SetParent(nil); err != nil {
+		t.Error("failed set parent:", err)
+	}
+	if err := b.SetParent(a); err != nil {
+		t.Error("failed set parent:", err)
+	}
+	if err := root.AddInstance(b); err != nil {
+		t.Error("failed moving instance within root:", err)
+	}
+
+	free := refInst("Free", "RBXF", nil)
+	refInst("Child", "RBXF", free)
+	if err := NewInstance("Parent", nil).AddChild(free); err != nil {
+		t.Error("unexpected error outside of root:", err)
+	}
+}
+
+func TestRoot_CloneInPlace(t *testing.T) {
+	a := refInst("A", "RBXA", nil)
+	b := refInst("B", "RBXB", a)
+	c := refInst("C", "RBXC", b)
+	b.Set("Target", ValueReference{Instance: c})
+	root := &Root{ReferenceGenerator: &SequentialReferences{}}
+	if err := root.AddInstance(a); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := b.Clone()
+	if err := a.AddChild(clone); err != nil {
+		t.Fatal("failed to add clone:", err)
+	}
+	if clone.Reference != "RBX0" || clone.Children[0].Reference != "RBX1" {
+		t.Errorf("unexpected references %q, %q", clone.Reference, clone.Children[0].Reference)
+	}
+	if root.GetByReference(clone.Reference) != clone || root.GetByReference("RBXB") != b {
+		t.Error("expected original and clone to be indexed")
+	}
+	if v := clone.Get("Target").(ValueReference); v.Instance != clone.Children[0] {
+		t.Error("expected reference to point into clone")
+	}
+
+	if err := b.Clone().SetParent(a); err != nil {
+		t.Error("failed to set parent of clone:", err)
+	}
+	if err := root.AddInstance(a.Clone()); err != nil {
+		t.Error("failed to add clone to root:", err)
+	}
+}
@@ -0,0 +1,1006 @@
+package rbxfile
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseValue parses a string into a Value of the given Type. The string is
+// expected to be in the form returned by the String method of the value, so
+// that ParseValue(v.Type(), v.String()) produces a value equal to v. Spaces
+// around components are ignored.
+//
+// Some types cannot be fully represented by a string. A Reference can only be
+// parsed from "<nil>" or an empty string, which produces a nil reference. A
+// ContentData is formatted as its Hash alone, so it is parsed into a value
+// with that Hash and no Data; a value with Data does not survive the round
+// trip.
+func ParseValue(typ Type, s string) (Value, error) {
+	parse, ok := valueParsers[typ]
+	if !ok {
+		return nil, fmt.Errorf("cannot parse value of type %s", typ)
+	}
+	v, err := parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", typ, err)
+	}
+	return v, nil
+}
+
+type valueParser func(s string) (Value, error)
+
+var valueParsers = map[Type]valueParser{
+	TypeString:             parseValueString,
+	TypeBinaryString:       parseValueBinaryString,
+	TypeProtectedString:    parseValueProtectedString,
+	TypeContent:            parseValueContent,
+	TypeBool:               parseValueBool,
+	TypeInt:                parseValueInt,
+	TypeFloat:              parseValueFloat,
+	TypeDouble:             parseValueDouble,
+	TypeUDim:               parseValueUDim,
+	TypeUDim2:              parseValueUDim2,
+	TypeRay:                parseValueRay,
+	TypeFaces:              parseValueFaces,
+	TypeAxes:               parseValueAxes,
+	TypeBrickColor:         parseValueBrickColor,
+	TypeColor3:             parseValueColor3,
+	TypeVector2:            parseValueVector2,
+	TypeVector3:            parseValueVector3,
+	TypeCFrame:             parseValueCFrame,
+	TypeToken:              parseValueToken,
+	TypeReference:          parseValueReference,
+	TypeVector3int16:       parseValueVector3int16,
+	TypeVector2int16:       parseValueVector2int16,
+	TypeNumberSequence:     parseValueNumberSequence,
+	TypeColorSequence:      parseValueColorSequence,
+	TypeNumberRange:        parseValueNumberRange,
+	TypeRect2D:             parseValueRect2D,
+	TypePhysicalProperties: parseValuePhysicalProperties,
+	TypeColor3uint8:        parseValueColor3uint8,
+	TypeInt64:              parseValueInt64,
+	TypeSharedString:       parseValueSharedString,
+	TypeContentData:        parseValueContentData,
+}
+
+// splitList splits s into n components separated by commas.
+func splitList(s string, n int) ([]string, error) {
+	list := strings.Split(s, ",")
+	if len(list) != n {
+		return nil, fmt.Errorf("expected %d components, got %d", n, len(list))
+	}
+	for i, c := range list {
+		list[i] = strings.TrimSpace(c)
+	}
+	return list, nil
+}
+
+// splitGroups splits s into n groups of the form "{...}", separated by commas,
+// returning the content of each group.
+func splitGroups(s string, n int) ([]string, error) {
+	groups := make([]string, 0, n)
+	s = strings.TrimSpace(s)
+	for s != "" {
+		if len(groups) > 0 {
+			if s[0] != ',' {
+				return nil, errors.New("expected comma between groups")
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+		if s == "" || s[0] != '{' {
+			return nil, errors.New("expected group")
+		}
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return nil, errors.New("unterminated group")
+		}
+		groups = append(groups, s[1:end])
+		s = strings.TrimSpace(s[end+1:])
+	}
+	if len(groups) != n {
+		return nil, fmt.Errorf("expected %d groups, got %d", n, len(groups))
+	}
+	return groups, nil
+}
+
+// parseFloats parses a list of n float32 components separated by commas.
+func parseFloats(s string, n int) ([]float32, error) {
+	list, err := splitList(s, n)
+	if err != nil {
+		return nil, err
+	}
+	return parseFloatList(list)
+}
+
+// parseFloatList parses each string in list as a float32.
+func parseFloatList(list []string) ([]float32, error) {
+	f := make([]float32, len(list))
+	for i, c := range list {
+		v, err := strconv.ParseFloat(c, 32)
+		if err != nil {
+			return nil, err
+		}
+		f[i] = float32(v)
+	}
+	return f, nil
+}
+
+// parseInts parses a list of n integer components separated by commas, each
+// with the given bit size.
+func parseInts(s string, n int, bitSize int) ([]int64, error) {
+	list, err := splitList(s, n)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]int64, n)
+	for i, c := range list {
+		if v[i], err = strconv.ParseInt(c, 10, bitSize); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// parseFlags parses a list of names separated by commas, setting the flag
+// that corresponds to each name. An empty string sets no flags.
+func parseFlags(s string, flags map[string]*bool) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		flag, ok := flags[name]
+		if !ok {
+			return fmt.Errorf("unknown name %q", name)
+		}
+		*flag = true
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////
+// Values
+
+////////////////
+
+func parseValueString(s string) (Value, error) {
+	return ValueString(s), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueString) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueString) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeString, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueString)
+	return nil
+}
+
+////////////////
+
+func parseValueBinaryString(s string) (Value, error) {
+	return ValueBinaryString(s), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueBinaryString) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueBinaryString) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeBinaryString, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueBinaryString)
+	return nil
+}
+
+////////////////
+
+func parseValueProtectedString(s string) (Value, error) {
+	return ValueProtectedString(s), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueProtectedString) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueProtectedString) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeProtectedString, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueProtectedString)
+	return nil
+}
+
+////////////////
+
+func parseValueContent(s string) (Value, error) {
+	return ValueContent(s), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueContent) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueContent) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeContent, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueContent)
+	return nil
+}
+
+////////////////
+
+func parseValueBool(s string) (Value, error) {
+	v, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return ValueBool(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueBool) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueBool) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeBool, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueBool)
+	return nil
+}
+
+////////////////
+
+func parseValueInt(s string) (Value, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ValueInt(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueInt) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueInt) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeInt, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueInt)
+	return nil
+}
+
+////////////////
+
+func parseValueFloat(s string) (Value, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 32)
+	if err != nil {
+		return nil, err
+	}
+	return ValueFloat(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueFloat) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueFloat) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeFloat, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueFloat)
+	return nil
+}
+
+////////////////
+
+func parseValueDouble(s string) (Value, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil, err
+	}
+	return ValueDouble(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueDouble) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueDouble) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeDouble, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueDouble)
+	return nil
+}
+
+////////////////
+
+func parseValueUDim(s string) (Value, error) {
+	list, err := splitList(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := strconv.ParseFloat(list[0], 32)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := strconv.ParseInt(list[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ValueUDim{Scale: float32(scale), Offset: int32(offset)}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueUDim) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueUDim) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeUDim, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueUDim)
+	return nil
+}
+
+////////////////
+
+func parseValueUDim2(s string) (Value, error) {
+	groups, err := splitGroups(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	x, err := parseValueUDim(groups[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := parseValueUDim(groups[1])
+	if err != nil {
+		return nil, err
+	}
+	return ValueUDim2{X: x.(ValueUDim), Y: y.(ValueUDim)}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueUDim2) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueUDim2) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeUDim2, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueUDim2)
+	return nil
+}
+
+////////////////
+
+func parseValueRay(s string) (Value, error) {
+	groups, err := splitGroups(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	origin, err := parseValueVector3(groups[0])
+	if err != nil {
+		return nil, err
+	}
+	direction, err := parseValueVector3(groups[1])
+	if err != nil {
+		return nil, err
+	}
+	return ValueRay{Origin: origin.(ValueVector3), Direction: direction.(ValueVector3)}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueRay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueRay) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeRay, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueRay)
+	return nil
+}
+
+////////////////
+
+func parseValueFaces(s string) (Value, error) {
+	var v ValueFaces
+	err := parseFlags(s, map[string]*bool{
+		"Right":  &v.Right,
+		"Top":    &v.Top,
+		"Back":   &v.Back,
+		"Left":   &v.Left,
+		"Bottom": &v.Bottom,
+		"Front":  &v.Front,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueFaces) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueFaces) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeFaces, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueFaces)
+	return nil
+}
+
+////////////////
+
+func parseValueAxes(s string) (Value, error) {
+	var v ValueAxes
+	err := parseFlags(s, map[string]*bool{
+		"X": &v.X,
+		"Y": &v.Y,
+		"Z": &v.Z,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueAxes) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueAxes) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeAxes, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueAxes)
+	return nil
+}
+
+////////////////
+
+func parseValueBrickColor(s string) (Value, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ValueBrickColor(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueBrickColor) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueBrickColor) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeBrickColor, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueBrickColor)
+	return nil
+}
+
+////////////////
+
+func parseValueColor3(s string) (Value, error) {
+	f, err := parseFloats(s, 3)
+	if err != nil {
+		return nil, err
+	}
+	return ValueColor3{R: f[0], G: f[1], B: f[2]}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueColor3) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueColor3) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeColor3, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueColor3)
+	return nil
+}
+
+////////////////
+
+func parseValueVector2(s string) (Value, error) {
+	f, err := parseFloats(s, 2)
+	if err != nil {
+		return nil, err
+	}
+	return ValueVector2{X: f[0], Y: f[1]}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueVector2) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueVector2) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeVector2, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueVector2)
+	return nil
+}
+
+////////////////
+
+func parseValueVector3(s string) (Value, error) {
+	f, err := parseFloats(s, 3)
+	if err != nil {
+		return nil, err
+	}
+	return ValueVector3{X: f[0], Y: f[1], Z: f[2]}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueVector3) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueVector3) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeVector3, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueVector3)
+	return nil
+}
+
+////////////////
+
+func parseValueCFrame(s string) (Value, error) {
+	f, err := parseFloats(s, 12)
+	if err != nil {
+		return nil, err
+	}
+	v := ValueCFrame{Position: ValueVector3{X: f[0], Y: f[1], Z: f[2]}}
+	copy(v.Rotation[:], f[3:])
+	return v, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueCFrame) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueCFrame) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeCFrame, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueCFrame)
+	return nil
+}
+
+////////////////
+
+func parseValueToken(s string) (Value, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ValueToken(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueToken) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueToken) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeToken, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueToken)
+	return nil
+}
+
+////////////////
+
+func parseValueReference(s string) (Value, error) {
+	switch strings.TrimSpace(s) {
+	case "", "<nil>":
+		return ValueReference{}, nil
+	}
+	return nil, errors.New("cannot refer to an instance by name")
+}
+
+// MarshalText implements encoding.TextMarshaler. Only a nil reference can be
+// represented as text.
+func (t ValueReference) MarshalText() ([]byte, error) {
+	if t.Instance != nil {
+		return nil, errors.New("cannot marshal reference to instance as text")
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueReference) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeReference, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueReference)
+	return nil
+}
+
+////////////////
+
+func parseValueVector3int16(s string) (Value, error) {
+	v, err := parseInts(s, 3, 16)
+	if err != nil {
+		return nil, err
+	}
+	return ValueVector3int16{X: int16(v[0]), Y: int16(v[1]), Z: int16(v[2])}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueVector3int16) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueVector3int16) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeVector3int16, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueVector3int16)
+	return nil
+}
+
+////////////////
+
+func parseValueVector2int16(s string) (Value, error) {
+	v, err := parseInts(s, 2, 16)
+	if err != nil {
+		return nil, err
+	}
+	return ValueVector2int16{X: int16(v[0]), Y: int16(v[1])}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueVector2int16) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueVector2int16) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeVector2int16, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueVector2int16)
+	return nil
+}
+
+////////////////
+
+func parseValueNumberSequence(s string) (Value, error) {
+	f, err := parseFloatList(strings.Fields(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(f)%3 != 0 {
+		return nil, errors.New("expected 3 components per keypoint")
+	}
+	v := make(ValueNumberSequence, len(f)/3)
+	for i := range v {
+		v[i] = ValueNumberSequenceKeypoint{Time: f[i*3], Value: f[i*3+1], Envelope: f[i*3+2]}
+	}
+	return v, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueNumberSequence) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueNumberSequence) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeNumberSequence, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueNumberSequence)
+	return nil
+}
+
+////////////////
+
+func parseValueColorSequence(s string) (Value, error) {
+	f, err := parseFloatList(strings.Fields(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(f)%5 != 0 {
+		return nil, errors.New("expected 5 components per keypoint")
+	}
+	v := make(ValueColorSequence, len(f)/5)
+	for i := range v {
+		k := f[i*5 : i*5+5]
+		v[i] = ValueColorSequenceKeypoint{
+			Time:     k[0],
+			Value:    ValueColor3{R: k[1], G: k[2], B: k[3]},
+			Envelope: k[4],
+		}
+	}
+	return v, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueColorSequence) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueColorSequence) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeColorSequence, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueColorSequence)
+	return nil
+}
+
+////////////////
+
+func parseValueNumberRange(s string) (Value, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected 2 components, got %d", len(fields))
+	}
+	f, err := parseFloatList(fields)
+	if err != nil {
+		return nil, err
+	}
+	return ValueNumberRange{Min: f[0], Max: f[1]}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueNumberRange) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueNumberRange) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeNumberRange, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueNumberRange)
+	return nil
+}
+
+////////////////
+
+func parseValueRect2D(s string) (Value, error) {
+	f, err := parseFloats(s, 4)
+	if err != nil {
+		return nil, err
+	}
+	return ValueRect2D{
+		Min: ValueVector2{X: f[0], Y: f[1]},
+		Max: ValueVector2{X: f[2], Y: f[3]},
+	}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueRect2D) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueRect2D) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeRect2D, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueRect2D)
+	return nil
+}
+
+////////////////
+
+func parseValuePhysicalProperties(s string) (Value, error) {
+	if strings.TrimSpace(s) == "nil" {
+		return ValuePhysicalProperties{}, nil
+	}
+	f, err := parseFloats(s, 5)
+	if err != nil {
+		return nil, err
+	}
+	return ValuePhysicalProperties{
+		CustomPhysics:    true,
+		Density:          f[0],
+		Friction:         f[1],
+		Elasticity:       f[2],
+		FrictionWeight:   f[3],
+		ElasticityWeight: f[4],
+	}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValuePhysicalProperties) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValuePhysicalProperties) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypePhysicalProperties, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValuePhysicalProperties)
+	return nil
+}
+
+////////////////
+
+func parseValueColor3uint8(s string) (Value, error) {
+	list, err := splitList(s, 3)
+	if err != nil {
+		return nil, err
+	}
+	var c [3]byte
+	for i, s := range list {
+		v, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = byte(v)
+	}
+	return ValueColor3uint8{R: c[0], G: c[1], B: c[2]}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueColor3uint8) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueColor3uint8) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeColor3uint8, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueColor3uint8)
+	return nil
+}
+
+////////////////
+
+func parseValueInt64(s string) (Value, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return ValueInt64(v), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueInt64) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueInt64) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeInt64, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueInt64)
+	return nil
+}
+
+////////////////
+
+func parseValueSharedString(s string) (Value, error) {
+	return ValueSharedString(s), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t ValueSharedString) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueSharedString) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeSharedString, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueSharedString)
+	return nil
+}
+
+////////////////
+
+func parseValueContentData(s string) (Value, error) {
+	return ValueContentData{Hash: s}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler. Only a value without Data
+// can be represented as text.
+func (t ValueContentData) MarshalText() ([]byte, error) {
+	if t.Data != nil {
+		return nil, errors.New("cannot marshal content data as text")
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *ValueContentData) UnmarshalText(text []byte) error {
+	v, err := ParseValue(TypeContentData, string(text))
+	if err != nil {
+		return err
+	}
+	*t = v.(ValueContentData)
+	return nil
+}
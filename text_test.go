@@ -0,0 +1,141 @@
+package rbxfile
+
+import (
+	"encoding"
+	"reflect"
+	"testing"
+)
+
+var textTestValues = []Value{
+	ValueString("hello, world"),
+	ValueBinaryString("\x00\x01\x02"),
+	ValueProtectedString("print(1)"),
+	ValueContent("rbxassetid://1"),
+	ValueBool(true),
+	ValueInt(-42),
+	ValueFloat(0.1),
+	ValueDouble(-1.0 / 3),
+	ValueUDim{Scale: 0.5, Offset: 10},
+	ValueUDim2{X: ValueUDim{Scale: 0.5, Offset: 10}, Y: ValueUDim{Scale: -1, Offset: 0}},
+	ValueRay{Origin: ValueVector3{X: 1, Y: 2, Z: 3}, Direction: ValueVector3{X: 0, Y: -1, Z: 0}},
+	ValueFaces{Right: true, Back: true, Front: true},
+	ValueFaces{},
+	ValueAxes{X: true, Z: true},
+	ValueBrickColor(194),
+	ValueColor3{R: 1, G: 0.5, B: 0.25},
+	ValueVector2{X: -1.5, Y: 2},
+	ValueVector3{X: 1, Y: 2, Z: 3},
+	ValueCFrame{Position: ValueVector3{X: 1, Y: 2, Z: 3}, Rotation: [9]float32{1, 0, 0, 0, 0, -1, 0, 1, 0}},
+	ValueToken(4294967295),
+	ValueReference{},
+	ValueVector3int16{X: -32768, Y: 0, Z: 32767},
+	ValueVector2int16{X: 1, Y: -1},
+	ValueNumberSequence{{Time: 0, Value: 1, Envelope: 0}, {Time: 1, Value: 0.5, Envelope: 0.1}},
+	ValueNumberSequence{},
+	ValueColorSequence{{Time: 0, Value: ValueColor3{R: 1}, Envelope: 0}, {Time: 1, Value: ValueColor3{B: 1}, Envelope: 0}},
+	ValueNumberRange{Min: -1, Max: 1},
+	ValueRect2D{Min: ValueVector2{X: 0, Y: 1}, Max: ValueVector2{X: 2, Y: 3}},
+	ValuePhysicalProperties{CustomPhysics: true, Density: 0.7, Friction: 0.3, Elasticity: 0.5, FrictionWeight: 1, ElasticityWeight: 1},
+	ValuePhysicalProperties{},
+	ValueColor3uint8{R: 255, G: 128, B: 0},
+	ValueInt64(-9007199254740993),
+	ValueSharedString("shared"),
+	ValueContentData{Hash: "abc"},
+}
+
+func TestParseValue(t *testing.T) {
+	covered := map[Type]bool{}
+	for _, v := range textTestValues {
+		covered[v.Type()] = true
+		p, err := ParseValue(v.Type(), v.String())
+		if err != nil {
+			t.Errorf("%s: parse %q: %s", v.Type(), v.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(p, v) {
+			t.Errorf("%s: parse %q: got %#v, expected %#v", v.Type(), v.String(), p, v)
+		}
+	}
+	for typ := range typeStrings {
+		if !covered[typ] {
+			t.Errorf("type %s not tested", typ)
+		}
+	}
+
+	for _, s := range []struct {
+		typ Type
+		s   string
+		v   Value
+	}{
+		{TypeVector3, "1,2,  3 ", ValueVector3{X: 1, Y: 2, Z: 3}},
+		{TypeUDim2, "{0.5,10},{0,0}", ValueUDim2{X: ValueUDim{Scale: 0.5, Offset: 10}}},
+		{TypeBool, " false", ValueBool(false)},
+		{TypeReference, "", ValueReference{}},
+		// Data has no text form.
+		{TypeContentData, ValueContentData{Hash: "abc", Data: []byte{1, 2}}.String(), ValueContentData{Hash: "abc"}},
+	} {
+		if v, err := ParseValue(s.typ, s.s); err != nil || !reflect.DeepEqual(v, s.v) {
+			t.Errorf("%s: parse %q: got %#v, %v", s.typ, s.s, v, err)
+		}
+	}
+
+	for _, s := range []struct {
+		typ Type
+		s   string
+	}{
+		{TypeInvalid, ""},
+		{TypeInt, "1.5"},
+		{TypeInt, "2147483648"},
+		{TypeVector3, "1, 2"},
+		{TypeVector3, "1, 2, x"},
+		{TypeUDim2, "{0.5, 10}"},
+		{TypeUDim2, "{0.5, 10}, {0, 0"},
+		{TypeFaces, "Front, Up"},
+		{TypeNumberSequence, "0 1"},
+		{TypeColor3uint8, "256, 0, 0"},
+		{TypeReference, "Part"},
+	} {
+		if _, err := ParseValue(s.typ, s.s); err == nil {
+			t.Errorf("%s: expected error parsing %q", s.typ, s.s)
+		}
+	}
+}
+
+func TestValueText(t *testing.T) {
+	for _, v := range textTestValues {
+		m, ok := v.(encoding.TextMarshaler)
+		if !ok {
+			t.Errorf("%s: not a TextMarshaler", v.Type())
+			continue
+		}
+		text, err := m.MarshalText()
+		if err != nil {
+			t.Errorf("%s: marshal: %s", v.Type(), err)
+			continue
+		}
+		p := reflect.New(reflect.TypeOf(v))
+		u, ok := p.Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			t.Errorf("%s: not a TextUnmarshaler", v.Type())
+			continue
+		}
+		if err := u.UnmarshalText(text); err != nil {
+			t.Errorf("%s: unmarshal: %s", v.Type(), err)
+			continue
+		}
+		if !reflect.DeepEqual(p.Elem().Interface(), v) {
+			t.Errorf("%s: got %#v, expected %#v", v.Type(), p.Elem().Interface(), v)
+		}
+	}
+
+	if _, err := (ValueReference{Instance: NewInstance("Part", nil)}).MarshalText(); err == nil {
+		t.Error("expected error marshaling reference to instance")
+	}
+	if _, err := (ValueContentData{Hash: "abc", Data: []byte{1, 2}}).MarshalText(); err == nil {
+		t.Error("expected error marshaling content data")
+	}
+	var v ValueVector3
+	if err := v.UnmarshalText([]byte("1, 2")); err == nil {
+		t.Error("expected error unmarshaling malformed text")
+	}
+}
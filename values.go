@@ -51,6 +51,7 @@ const (
 	TypeColor3uint8
 	TypeInt64
 	TypeSharedString
+	TypeContentData
 )
 
 // TypeFromString returns a Type from its string representation. TypeInvalid
@@ -118,6 +119,7 @@ var typeStrings = map[Type]string{
 	TypeColor3uint8:        "Color3uint8",
 	TypeInt64:              "Int64",
 	TypeSharedString:       "SharedString",
+	TypeContentData:        "ContentData",
 }
 
 // Value holds a value of a particular Type.
@@ -176,6 +178,7 @@ var valueGenerators = map[Type]valueGenerator{
 	TypeColor3uint8:        newValueColor3uint8,
 	TypeInt64:              newValueInt64,
 	TypeSharedString:       newValueSharedString,
+	TypeContentData:        newValueContentData,
 }
 
 func joinstr(a ...string) string {
@@ -948,3 +951,38 @@ func (t ValueSharedString) Copy() Value {
 	copy(c, t)
 	return c
 }
+
+////////////////
+
+// ValueContentData is a Content value whose asset data is embedded in the
+// file, rather than referred to by URL. Data holds the raw payload, while
+// Hash holds an identifier of the data, if one was given.
+//
+// Only the XML format can represent ValueContentData. The binary format
+// encodes it lossily: a value with Data is encoded as a ValueSharedString of
+// the data, discarding Hash, while a value without Data is encoded as a
+// ValueString of the hash. Decoding such a file yields those types rather
+// than ValueContentData.
+type ValueContentData struct {
+	Hash string
+	Data []byte
+}
+
+func newValueContentData() Value {
+	return ValueContentData{}
+}
+
+func (ValueContentData) Type() Type {
+	return TypeContentData
+}
+func (t ValueContentData) String() string {
+	return t.Hash
+}
+func (t ValueContentData) Copy() Value {
+	c := t
+	if t.Data != nil {
+		c.Data = make([]byte, len(t.Data))
+		copy(c.Data, t.Data)
+	}
+	return c
+}
@@ -83,6 +83,7 @@ func TestValueString(t *testing.T) {
 		{ValueBinaryString("test\000string"), "test\000string"},
 		{ValueProtectedString("test\000string"), "test\000string"},
 		{ValueContent("test\000string"), "test\000string"},
+		{ValueContentData{Hash: "abc123", Data: []byte("data")}, "abc123"},
 
 		{ValueBool(true), "true"},
 		{ValueBool(false), "false"},
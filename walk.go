@@ -0,0 +1,139 @@
+package rbxfile
+
+// WalkAction indicates how a walk proceeds after visiting an instance.
+type WalkAction int
+
+const (
+	// WalkContinue continues the walk normally.
+	WalkContinue WalkAction = iota
+
+	// WalkSkip skips the descendants of the instance. When returned after
+	// the descendants have been visited, it is the same as WalkContinue.
+	WalkSkip
+
+	// WalkStop ends the walk immediately.
+	WalkStop
+)
+
+// WalkFunc is called for each instance visited by Walk. depth is the depth of
+// the instance relative to where the walk began, where the starting instances
+// have a depth of 0.
+type WalkFunc func(inst *Instance, depth int) WalkAction
+
+// Walk visits the instance and each of its descendants in depth-first order.
+// If pre is not nil, it is called for an instance before its descendants are
+// visited. If post is not nil, it is called for an instance after its
+// descendants are visited. The children of an instance are read after pre
+// returns, so pre may modify them. Otherwise, the tree should not be modified
+// during the walk.
+//
+// Returns false if the walk was stopped by WalkStop.
+func (inst *Instance) Walk(pre, post WalkFunc) bool {
+	return inst.walk(pre, post, 0)
+}
+
+func (inst *Instance) walk(pre, post WalkFunc, depth int) bool {
+	if pre != nil {
+		switch pre(inst, depth) {
+		case WalkStop:
+			return false
+		case WalkSkip:
+			goto finish
+		}
+	}
+	for _, child := range inst.Children {
+		if !child.walk(pre, post, depth+1) {
+			return false
+		}
+	}
+finish:
+	if post != nil && post(inst, depth) == WalkStop {
+		return false
+	}
+	return true
+}
+
+// Walk calls Instance.Walk on each root instance, in order. Each root
+// instance has a depth of 0. Returns false if the walk was stopped by
+// WalkStop.
+func (root *Root) Walk(pre, post WalkFunc) bool {
+	for _, inst := range root.Instances {
+		if !inst.walk(pre, post, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetDescendants returns a list of every descendant of the instance, in
+// depth-first, pre-order.
+func (inst *Instance) GetDescendants() []*Instance {
+	descendants := []*Instance{}
+	for _, child := range inst.Children {
+		child.Walk(func(desc *Instance, depth int) WalkAction {
+			descendants = append(descendants, desc)
+			return WalkContinue
+		}, nil)
+	}
+	return descendants
+}
+
+// GetDescendants returns a list of every instance in the tree, in
+// depth-first, pre-order.
+func (root *Root) GetDescendants() []*Instance {
+	descendants := []*Instance{}
+	root.Walk(func(inst *Instance, depth int) WalkAction {
+		descendants = append(descendants, inst)
+		return WalkContinue
+	}, nil)
+	return descendants
+}
+
+// Note: Roblox's class-based methods also match subclasses of the given class.
+// Since this would require information about the class hierarchy, the
+// following methods match ClassName exactly.
+
+// FindFirstChildOfClass returns the first child whose ClassName matches the
+// given class name. Returns nil if no child was found.
+func (inst *Instance) FindFirstChildOfClass(className string) *Instance {
+	for _, child := range inst.Children {
+		if child.ClassName == className {
+			return child
+		}
+	}
+	return nil
+}
+
+// GetChildrenOfClass returns a list of the children whose ClassName matches
+// the given class name.
+func (inst *Instance) GetChildrenOfClass(className string) []*Instance {
+	children := []*Instance{}
+	for _, child := range inst.Children {
+		if child.ClassName == className {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// FindFirstAncestor returns the closest ancestor whose Name property matches
+// the given name. Returns nil if no ancestor was found.
+func (inst *Instance) FindFirstAncestor(name string) *Instance {
+	for parent := inst.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Name() == name {
+			return parent
+		}
+	}
+	return nil
+}
+
+// FindFirstAncestorOfClass returns the closest ancestor whose ClassName
+// matches the given class name. Returns nil if no ancestor was found.
+func (inst *Instance) FindFirstAncestorOfClass(className string) *Instance {
+	for parent := inst.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.ClassName == className {
+			return parent
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package rbxfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func walkTestRoot() *Root {
+	model := NewInstance("Model", nil)
+	model.SetName("A")
+	part := NewInstance("Part", model)
+	part.SetName("B")
+	NewInstance("Decal", part).SetName("C")
+	NewInstance("Part", model).SetName("D")
+	folder := NewInstance("Folder", nil)
+	folder.SetName("E")
+	return &Root{Instances: []*Instance{model, folder}}
+}
+
+func TestWalk(t *testing.T) {
+	root := walkTestRoot()
+	var pre, post []string
+	root.Walk(func(inst *Instance, depth int) WalkAction {
+		pre = append(pre, inst.Name()+strings.Repeat("+", depth))
+		return WalkContinue
+	}, func(inst *Instance, depth int) WalkAction {
+		post = append(post, inst.Name())
+		return WalkContinue
+	})
+	if got := strings.Join(pre, " "); got != "A B+ C++ D+ E" {
+		t.Errorf("unexpected pre-order %q", got)
+	}
+	if got := strings.Join(post, " "); got != "C B D A E" {
+		t.Errorf("unexpected post-order %q", got)
+	}
+
+	var visited []string
+	completed := root.Walk(func(inst *Instance, depth int) WalkAction {
+		visited = append(visited, inst.Name())
+		switch inst.Name() {
+		case "B":
+			return WalkSkip
+		case "D":
+			return WalkStop
+		}
+		return WalkContinue
+	}, nil)
+	if completed || strings.Join(visited, " ") != "A B D" {
+		t.Errorf("unexpected walk %v (completed: %t)", visited, completed)
+	}
+
+	var names []string
+	for _, inst := range root.GetDescendants() {
+		names = append(names, inst.Name())
+	}
+	if got := strings.Join(names, " "); got != "A B C D E" {
+		t.Errorf("unexpected descendants %q", got)
+	}
+	if n := len(root.Instances[0].GetDescendants()); n != 3 {
+		t.Errorf("expected 3 descendants, got %d", n)
+	}
+}
+
+func TestFindOfClass(t *testing.T) {
+	root := walkTestRoot()
+	model := root.Instances[0]
+	decal := model.Children[0].Children[0]
+
+	if inst := model.FindFirstChildOfClass("Part"); inst == nil || inst.Name() != "B" {
+		t.Errorf("unexpected child %v", inst)
+	}
+	if inst := model.FindFirstChildOfClass("Decal"); inst != nil {
+		t.Errorf("expected no child, got %v", inst)
+	}
+	if parts := model.GetChildrenOfClass("Part"); len(parts) != 2 {
+		t.Errorf("expected 2 parts, got %d", len(parts))
+	}
+	if inst := decal.FindFirstAncestor("A"); inst != model {
+		t.Errorf("unexpected ancestor %v", inst)
+	}
+	if inst := decal.FindFirstAncestorOfClass("Part"); inst != model.Children[0] {
+		t.Errorf("unexpected ancestor %v", inst)
+	}
+	if inst := model.FindFirstAncestorOfClass("Model"); inst != nil {
+		t.Errorf("expected no ancestor, got %v", inst)
+	}
+}
@@ -2,6 +2,7 @@ package xml
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -49,12 +50,85 @@ type RobloxCodec struct {
 	// ExcludeMetadata determines whether <Meta> tags should be included while
 	// encoding.
 	ExcludeMetadata bool
+
+	// References is used to generate references for decoded instances that
+	// lack a referent, or whose referent duplicates an earlier one; other
+	// instances keep their referent. It is also set as the ReferenceGenerator
+	// of the decoded Root. When encoding, it is used to generate referents for instances
+	// whose reference is empty or duplicated. If nil, then the
+	// ReferenceGenerator of the Root being encoded is used, or
+	// rbxfile.CryptoReferences if that is also nil.
+	References rbxfile.ReferenceGenerator
+
+	// Canonical determines whether the output of encoding depends only on
+	// the content of the Root, so that encoding equivalent Roots produces
+	// identical documents. When true, the references of instances are
+	// ignored, and referents are instead numbered in tree order ("RBX0",
+	// "RBX1", and so on). Floating-point numbers are written with the
+	// shortest representation that decodes to the same value, rather than
+	// with a fixed precision. Shared strings are written once each to a
+	// SharedStrings tag, in order of their hash, and properties refer to them
+	// by hash. Otherwise, the value of a shared string is written directly
+	// within each property.
+	//
+	// Regardless of Canonical, properties are written in order of their name,
+	// and metadata in order of its key.
+	Canonical bool
+
+	// PreserveUnknown determines whether unrecognized content is preserved.
+	// When decoding, unrecognized attributes and child tags of the root tag
+	// and of Item tags, as well as property tags that could not be decoded,
+	// are stored as an *Unknown in the Extra field of the Root or Instance.
+	// When encoding, an *Unknown found in the Extra field of a Root or
+	// Instance is written back to the corresponding tag.
+	PreserveUnknown bool
 }
 
+// Unknown holds the content of a tag that was not recognized by
+// RobloxCodec. The content is written back unchanged, though not necessarily
+// in its original position: attributes and tags are added after those
+// produced by the encoder.
+type Unknown struct {
+	// Attr contains unrecognized attributes of the tag.
+	Attr []Attr
+
+	// Tags contains unrecognized child tags.
+	Tags []*Tag
+
+	// Properties contains property tags that could not be decoded. Only
+	// applies to Item tags.
+	Properties []*Tag
+}
+
+// knownRootAttr is the set of attributes of the root tag that are produced
+// by the encoder.
+var knownRootAttr = map[string]bool{
+	"xmlns:xmime":                   true,
+	"xmlns:xsi":                     true,
+	"xsi:noNamespaceSchemaLocation": true,
+	"version":                       true,
+}
+
+// unknownAttr returns the attributes of tag not present in known.
+func unknownAttr(tag *Tag, known map[string]bool) (attr []Attr) {
+	for _, a := range tag.Attr {
+		if !known[a.Name] {
+			attr = append(attr, a)
+		}
+	}
+	return attr
+}
+
+// Decode decodes a Document into a Root. The MaxInstances and MaxProperties
+// fields of document.Limits are honored, returning a rbxfile.ErrLimit if they
+// are exceeded.
 func (c RobloxCodec) Decode(document *Document) (root *rbxfile.Root, err error) {
 	if document == nil {
 		return nil, fmt.Errorf("document is nil")
 	}
+	if document.Root == nil {
+		return nil, fmt.Errorf("document has no root tag")
+	}
 
 	dec := &rdecoder{
 		document:   document,
@@ -63,8 +137,75 @@ func (c RobloxCodec) Decode(document *Document) (root *rbxfile.Root, err error)
 		instLookup: make(rbxfile.References),
 	}
 
-	dec.decode()
-	return dec.root, dec.err
+	if err = dec.decode(); err != nil {
+		return nil, err
+	}
+	return dec.root, nil
+}
+
+// DecodeStream reads a document from r, decoding each Item as soon as it is
+// closed, rather than after the entire document has been read. Decoded Item
+// tags are removed from the document, so that the tree of tags is never held
+// in memory in full. The Limits of document are honored, and its Warnings are
+// populated.
+func (c RobloxCodec) DecodeStream(document *Document, r io.Reader, stream Stream) (root *rbxfile.Root, err error) {
+	if document == nil {
+		return nil, fmt.Errorf("document is nil")
+	}
+
+	dec := &rdecoder{
+		document:   document,
+		codec:      c,
+		root:       &rbxfile.Root{ReferenceGenerator: c.References},
+		instLookup: make(rbxfile.References),
+	}
+
+	// Decoded instances that have yet to be added to their parent, by the tag
+	// of the parent.
+	pending := map[*Tag][]*rbxfile.Instance{}
+	_, err = document.ReadFromFunc(r, func(parent, tag *Tag) (keep bool, err error) {
+		if tag.StartName != "Item" {
+			return true, nil
+		}
+		children := pending[tag]
+		delete(pending, tag)
+		if parent != document.Root && parent.StartName != "Item" {
+			// Not decoded by getItems.
+			return false, nil
+		}
+		if stream.Filter != nil {
+			if className, _ := tag.AttrValue("class"); !stream.Filter(className) {
+				pending[parent] = append(pending[parent], children...)
+				return false, nil
+			}
+		}
+		instance := dec.getItem(tag)
+		if dec.err != nil {
+			return false, dec.err
+		}
+		if instance == nil {
+			return false, nil
+		}
+		for _, child := range children {
+			instance.AddChild(child)
+		}
+		if stream.Instance != nil {
+			if err := stream.Instance(instance); err != nil {
+				return false, err
+			}
+		}
+		pending[parent] = append(pending[parent], instance)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dec.root.Instances = pending[document.Root]
+	if err = dec.decodeRoot(); err != nil {
+		return nil, err
+	}
+	return dec.root, nil
 }
 
 func generateClassMembers(api rbxapi.Root, className string) map[string]rbxapi.Property {
@@ -97,6 +238,12 @@ type rdecoder struct {
 	instLookup rbxfile.References
 	propRefs   []rbxfile.PropRef
 	stringRefs []rbxfile.PropRef
+	instCount  int64
+	propCount  int64
+
+	// undecoded accumulates property tags that could not be decoded, when
+	// PreserveUnknown is set.
+	undecoded []*Tag
 }
 
 func (dec *rdecoder) decode() error {
@@ -104,11 +251,29 @@ func (dec *rdecoder) decode() error {
 		return dec.err
 	}
 
-	dec.root = new(rbxfile.Root)
+	dec.root = &rbxfile.Root{ReferenceGenerator: dec.codec.References}
 	dec.root.Instances, _ = dec.getItems(nil, dec.document.Root.Tags, nil)
+	if dec.err != nil {
+		return dec.err
+	}
+	return dec.decodeRoot()
+}
 
+// decodeRoot decodes the content of the root tag other than Items, then
+// resolves references.
+func (dec *rdecoder) decodeRoot() error {
+	dec.root.FixTree()
+	var unknown Unknown
+	if dec.codec.PreserveUnknown {
+		unknown.Attr = unknownAttr(dec.document.Root, knownRootAttr)
+	}
 	for _, tag := range dec.document.Root.Tags {
 		switch tag.StartName {
+		case "Item", "External":
+		default:
+			if dec.codec.PreserveUnknown {
+				unknown.Tags = append(unknown.Tags, tag)
+			}
 		case "Meta":
 			key, ok := tag.AttrValue("name")
 			if !ok {
@@ -144,10 +309,22 @@ func (dec *rdecoder) decode() error {
 		}
 	}
 
+	// A shared string that is not found in the SharedStrings tag holds its
+	// value directly.
+	for _, ref := range dec.stringRefs {
+		if _, ok := ref.Instance.Properties[ref.Property]; !ok {
+			ref.Instance.Properties[ref.Property] = rbxfile.ValueSharedString(ref.Reference)
+		}
+	}
+
 	for _, propRef := range dec.propRefs {
 		dec.instLookup.Resolve(propRef)
 	}
 
+	if unknown.Attr != nil || unknown.Tags != nil {
+		dec.root.Extra = &unknown
+	}
+
 	return nil
 }
 
@@ -156,39 +333,18 @@ func (dec *rdecoder) getItems(parent *rbxfile.Instance, tags []*Tag, classMember
 	hasProps := false
 
 	for _, tag := range tags {
+		if dec.err != nil {
+			return nil, nil
+		}
 		switch tag.StartName {
 		case "Item":
-			className, ok := tag.AttrValue("class")
-			if !ok {
-				dec.document.Warnings = append(dec.document.Warnings, errors.New("item with missing class attribute"))
-				continue
-			}
-
-			classMemb := generateClassMembers(dec.codec.API, className)
-			if dec.codec.API != nil {
-				if dec.codec.API.GetClass(className) == nil {
-					dec.document.Warnings = append(dec.document.Warnings, fmt.Errorf("invalid class name `%s`", className))
-					if dec.codec.ExcludeInvalidAPI {
-						continue
-					}
-				}
-			}
-
-			instance := rbxfile.NewInstance(className, nil)
-			referent, ok := tag.AttrValue("referent")
-			if ok && len(referent) > 0 {
-				instance.Reference = referent
-				if !rbxfile.IsEmptyReference(referent) {
-					dec.instLookup[referent] = instance
-				}
+			instance := dec.getItem(tag)
+			if dec.err != nil {
+				return nil, nil
 			}
-
-			var children []*rbxfile.Instance
-			children, instance.Properties = dec.getItems(instance, tag.Tags, classMemb)
-			for _, child := range children {
-				instance.AddChild(child)
+			if instance == nil {
+				continue
 			}
-
 			instances = append(instances, instance)
 
 		case "Properties":
@@ -198,6 +354,10 @@ func (dec *rdecoder) getItems(parent *rbxfile.Instance, tags []*Tag, classMember
 			hasProps = true
 
 			for _, property := range tag.Tags {
+				dec.propCount++
+				if dec.err = dec.document.Limits.Check(rbxfile.LimitProperties, dec.propCount); dec.err != nil {
+					return nil, nil
+				}
 				name, value, ok := dec.getProperty(property, parent, classMembers)
 				if ok {
 					properties[name] = value
@@ -209,6 +369,77 @@ func (dec *rdecoder) getItems(parent *rbxfile.Instance, tags []*Tag, classMember
 	return instances, properties
 }
 
+// getItem decodes an Item tag into an instance, along with any Items it
+// contains. Returns nil if the Item is skipped or an error occurs.
+func (dec *rdecoder) getItem(tag *Tag) *rbxfile.Instance {
+	dec.instCount++
+	if dec.err = dec.document.Limits.Check(rbxfile.LimitInstances, dec.instCount); dec.err != nil {
+		return nil
+	}
+
+	className, ok := tag.AttrValue("class")
+	if !ok {
+		dec.document.Warnings = append(dec.document.Warnings, errors.New("item with missing class attribute"))
+		return nil
+	}
+
+	classMemb := generateClassMembers(dec.codec.API, className)
+	if dec.codec.API != nil {
+		if dec.codec.API.GetClass(className) == nil {
+			dec.document.Warnings = append(dec.document.Warnings, fmt.Errorf("invalid class name `%s`", className))
+			if dec.codec.ExcludeInvalidAPI {
+				return nil
+			}
+		}
+	}
+
+	instance := rbxfile.NewInstance(className, nil)
+	referent, _ := tag.AttrValue("referent")
+	if _, dup := dec.instLookup[referent]; rbxfile.IsEmptyReference(referent) || dup {
+		// The instance lacks a usable referent, so a new reference is
+		// generated. Property references to a duplicated referent resolve
+		// to the first instance that has it.
+		if dec.codec.References != nil {
+			instance.Reference = dec.codec.References.GenerateReference()
+		}
+	} else {
+		instance.Reference = referent
+		dec.instLookup[referent] = instance
+	}
+
+	var children []*rbxfile.Instance
+	saved := dec.undecoded
+	dec.undecoded = nil
+	children, instance.Properties = dec.getItems(instance, tag.Tags, classMemb)
+	undecoded := dec.undecoded
+	dec.undecoded = saved
+	for _, child := range children {
+		instance.AddChild(child)
+	}
+
+	if dec.codec.PreserveUnknown {
+		unknown := &Unknown{
+			Attr:       unknownAttr(tag, map[string]bool{"class": true, "referent": true}),
+			Properties: undecoded,
+		}
+		hasProps := false
+		for _, sub := range tag.Tags {
+			switch {
+			case sub.StartName == "Item":
+			case sub.StartName == "Properties" && !hasProps:
+				hasProps = true
+			default:
+				unknown.Tags = append(unknown.Tags, sub)
+			}
+		}
+		if unknown.Attr != nil || unknown.Tags != nil || unknown.Properties != nil {
+			instance.Extra = unknown
+		}
+	}
+
+	return instance
+}
+
 // DecodeProperties decodes a list of tags as properties to a given instance.
 // Returns a list of unresolved references.
 func (c RobloxCodec) DecodeProperties(tags []*Tag, inst *rbxfile.Instance, refs rbxfile.References) (propRefs []rbxfile.PropRef) {
@@ -262,6 +493,9 @@ func (dec *rdecoder) getProperty(tag *Tag, instance *rbxfile.Instance, classMemb
 processValue:
 	value, ok = dec.getValue(tag, valueType, enum)
 	if !ok {
+		if dec.codec.PreserveUnknown {
+			dec.undecoded = append(dec.undecoded, tag)
+		}
 		return "", nil, false
 	}
 
@@ -448,12 +682,24 @@ func (dec *rdecoder) getValue(tag *Tag, valueType string, enum rbxapi.Enum) (val
 
 		for _, subtag := range tag.Tags {
 			switch subtag.StartName {
-			case "binary":
-				dec.document.Warnings = append(dec.document.Warnings, errors.New("not reading binary data"))
-				fallthrough
-			case "hash":
-				// Ignored.
-				fallthrough
+			case "binary", "hash":
+				// Data embedded in the file. Both tags may be present, in
+				// either order.
+				var v rbxfile.ValueContentData
+				for _, subtag := range tag.Tags {
+					switch subtag.StartName {
+					case "binary":
+						dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(getContent(subtag)))
+						b, err := ioutil.ReadAll(dec)
+						if err != nil {
+							return nil, false
+						}
+						v.Data = b
+					case "hash":
+						v.Hash = getContent(subtag)
+					}
+				}
+				return v, true
 			case "null":
 				//DIFF: If null tag has content, then `tag expected` error is
 				//thrown.
@@ -702,8 +948,11 @@ func (dec *rdecoder) getValue(tag *Tag, valueType string, enum rbxapi.Enum) (val
 			"FrictionWeight":   &v.FrictionWeight,
 			"ElasticityWeight": &v.ElasticityWeight,
 		}.getFrom(tag)
-		vb, _ := dec.getValue(cp, "bool", enum)
-		v.CustomPhysics = bool(vb.(rbxfile.ValueBool))
+		if cp != nil {
+			if vb, ok := dec.getValue(cp, "bool", enum); ok {
+				v.CustomPhysics = bool(vb.(rbxfile.ValueBool))
+			}
+		}
 		return v, true
 
 	case "Color3uint8":
@@ -836,21 +1085,80 @@ type rencoder struct {
 	codec         RobloxCodec
 	document      *Document
 	refs          rbxfile.References
+	refGen        rbxfile.ReferenceGenerator
+	canonRefs     map[*rbxfile.Instance]string
 	sharedStrings map[string][]byte
 	err           error
 }
 
 func (c RobloxCodec) Encode(root *rbxfile.Root) (document *Document, err error) {
+	enc := newRencoder(c, root)
+	enc.encode()
+	return enc.document, enc.err
+
+}
+
+// EncodeStream encodes root directly to w, without building a Document. The
+// output is identical to that produced by encoding root with Encode, then
+// writing the Document. The formatting of the output is determined by the
+// Prefix, Indent, Suffix and ExcludeRoot fields of document; if document is
+// nil, then the formatting of a Document returned by Encode is used. The Root
+// field of document is ignored, and Warnings is populated.
+func (c RobloxCodec) EncodeStream(w io.Writer, document *Document, root *rbxfile.Root) (err error) {
+	enc := newRencoder(c, root)
+	if document == nil {
+		document = newDocument()
+	}
+	enc.document = document
+	enc.prepare()
+
+	rootTag := enc.rootTag()
+	e, t := document.writeStart(w, rootTag)
+	for _, tag := range rootTag.Tags {
+		if t == nil || t.child(tag) < 0 {
+			break
+		}
+	}
+	for _, instance := range enc.root.Instances {
+		if t == nil || !enc.streamInstance(instance, t) {
+			break
+		}
+	}
+	if t != nil {
+		for _, tag := range enc.trailingTags() {
+			if t.child(tag) < 0 {
+				break
+			}
+		}
+	}
+	if _, err = e.writeEnd(t); err != nil {
+		return err
+	}
+	return enc.err
+}
+
+func newRencoder(c RobloxCodec, root *rbxfile.Root) *rencoder {
 	enc := &rencoder{
 		root:          root,
 		codec:         c,
 		refs:          make(rbxfile.References),
+		refGen:        c.References,
 		sharedStrings: map[string][]byte{},
 	}
+	if enc.refGen == nil && root != nil {
+		enc.refGen = root.ReferenceGenerator
+	}
+	return enc
+}
 
-	enc.encode()
-	return enc.document, enc.err
-
+// newDocument returns an empty Document with the formatting produced by
+// RobloxCodec.
+func newDocument() *Document {
+	return &Document{
+		Prefix: "",
+		Indent: "\t",
+		Suffix: "",
+	}
 }
 
 type sortTagsByNameAttr []*Tag
@@ -894,32 +1202,62 @@ func (w *wrapWriter) Write(p []byte) (n int, err error) {
 }
 
 func (enc *rencoder) encode() {
-	enc.document = &Document{
-		Prefix: "",
-		Indent: "\t",
-		Suffix: "",
-		Root:   NewRoot(),
+	enc.document = newDocument()
+	enc.prepare()
+	enc.document.Root = enc.rootTag()
+	for _, instance := range enc.root.Instances {
+		enc.encodeInstance(instance, enc.document.Root)
+	}
+	enc.document.Root.Tags = append(enc.document.Root.Tags, enc.trailingTags()...)
+}
+
+// prepare is called before instances are encoded.
+func (enc *rencoder) prepare() {
+	if enc.codec.Canonical {
+		// Number instances in tree order before encoding, so that referents
+		// do not depend on the order in which properties refer to them.
+		enc.canonRefs = map[*rbxfile.Instance]string{}
+		var walk func(instances []*rbxfile.Instance)
+		walk = func(instances []*rbxfile.Instance) {
+			for _, instance := range instances {
+				enc.reference(instance)
+				walk(instance.Children)
+			}
+		}
+		walk(enc.root.Instances)
 	}
+}
+
+// rootTag returns the root tag, containing the tags that precede instances.
+func (enc *rencoder) rootTag() *Tag {
+	root := NewRoot()
 	if !enc.codec.ExcludeMetadata {
-		enc.document.Root.Tags = make([]*Tag, 0, len(enc.root.Metadata))
+		root.Tags = make([]*Tag, 0, len(enc.root.Metadata))
 		for key, value := range enc.root.Metadata {
-			enc.document.Root.Tags = append(enc.document.Root.Tags, &Tag{
+			root.Tags = append(root.Tags, &Tag{
 				StartName: "Meta",
 				Attr:      []Attr{{Name: "name", Value: key}},
 				Text:      value,
 			})
 		}
-		sort.Sort(sortTagsByNameAttr(enc.document.Root.Tags))
+		sort.Sort(sortTagsByNameAttr(root.Tags))
 	}
 	if !enc.codec.ExcludeExternal {
-		enc.document.Root.Tags = append(enc.document.Root.Tags,
+		root.Tags = append(root.Tags,
 			&Tag{StartName: "External", Text: "null"},
 			&Tag{StartName: "External", Text: "nil"},
 		)
 	}
+	if unknown, ok := enc.root.Extra.(*Unknown); ok && enc.codec.PreserveUnknown {
+		root.Attr = append(root.Attr, unknown.Attr...)
+	}
+	return root
+}
 
-	for _, instance := range enc.root.Instances {
-		enc.encodeInstance(instance, enc.document.Root)
+// trailingTags returns the tags of the root tag that follow instances.
+func (enc *rencoder) trailingTags() (tags []*Tag) {
+	if unknown, ok := enc.root.Extra.(*Unknown); ok && enc.codec.PreserveUnknown {
+		tags = append(tags, unknown.Tags...)
 	}
 
 	if len(enc.sharedStrings) > 0 {
@@ -946,30 +1284,86 @@ func (enc *rencoder) encode() {
 			}
 			s.Reset()
 		}
+		tags = append(tags, tag)
 	}
+	return tags
+}
+
+// reference returns the referent of instance.
+func (enc *rencoder) reference(instance *rbxfile.Instance) string {
+	if enc.canonRefs == nil {
+		return enc.refs.GetWith(instance, enc.refGen)
+	}
+	ref, ok := enc.canonRefs[instance]
+	if !ok {
+		ref = "RBX" + strconv.Itoa(len(enc.canonRefs))
+		enc.canonRefs[instance] = ref
+	}
+	return ref
 }
 
 func (enc *rencoder) encodeInstance(instance *rbxfile.Instance, parent *Tag) {
+	item := enc.encodeItem(instance)
+	if item == nil {
+		return
+	}
+	parent.Tags = append(parent.Tags, item)
+
+	for _, child := range instance.Children {
+		enc.encodeInstance(child, item)
+	}
+}
+
+// streamInstance writes instance and its descendants as the next child of
+// parent. Returns false if an error occurred.
+func (enc *rencoder) streamInstance(instance *rbxfile.Instance, parent *tagWriter) bool {
+	item := enc.encodeItem(instance)
+	if item == nil {
+		return true
+	}
+	t, r := parent.startChild(item)
+	if r < 0 {
+		return false
+	} else if r == 0 {
+		return true
+	}
+	for _, tag := range item.Tags {
+		if t.child(tag) < 0 {
+			return false
+		}
+	}
+	for _, child := range instance.Children {
+		if !enc.streamInstance(child, t) {
+			return false
+		}
+	}
+	return t.end() >= 0
+}
+
+// encodeItem returns the Item tag of instance, excluding child instances.
+// Returns nil if the instance is excluded.
+func (enc *rencoder) encodeItem(instance *rbxfile.Instance) *Tag {
 	if enc.codec.API != nil {
 		if class := enc.codec.API.GetClass(instance.ClassName); class == nil {
 			enc.document.Warnings = append(enc.document.Warnings, fmt.Errorf("invalid class `%s`", instance.ClassName))
 			if enc.codec.ExcludeInvalidAPI {
-				return
+				return nil
 			}
 		}
 	}
 
-	ref := enc.refs.Get(instance)
+	ref := enc.reference(instance)
 	properties := enc.encodeProperties(instance)
 	item := NewItem(instance.ClassName, ref, properties...)
 	if enc.codec.ExcludeReferent {
 		item.SetAttrValue("referent", "")
 	}
-	parent.Tags = append(parent.Tags, item)
-
-	for _, child := range instance.Children {
-		enc.encodeInstance(child, item)
+	if unknown, ok := instance.Extra.(*Unknown); ok && enc.codec.PreserveUnknown {
+		item.Attr = append(item.Attr, unknown.Attr...)
+		item.Tags[0].Tags = append(item.Tags[0].Tags, unknown.Properties...)
+		item.Tags = append(item.Tags, unknown.Tags...)
 	}
+	return item
 }
 
 func (c RobloxCodec) EncodeProperties(instance *rbxfile.Instance) (properties []*Tag) {
@@ -993,6 +1387,7 @@ func (enc *rencoder) encodeProperties(instance *rbxfile.Instance) (properties []
 	}
 
 	// Sort properties by name
+	instance.LoadProperties()
 	sorted := make([]string, 0, len(instance.Properties))
 	for name := range instance.Properties {
 		sorted = append(sorted, name)
@@ -1110,18 +1505,18 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "CoordinateFrame",
 			Attr:      attr,
 			Tags: []*Tag{
-				&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.Position.X)},
-				&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Position.Y)},
-				&Tag{StartName: "Z", NoIndent: true, Text: encodeFloat(value.Position.Z)},
-				&Tag{StartName: "R00", NoIndent: true, Text: encodeFloat(value.Rotation[0])},
-				&Tag{StartName: "R01", NoIndent: true, Text: encodeFloat(value.Rotation[1])},
-				&Tag{StartName: "R02", NoIndent: true, Text: encodeFloat(value.Rotation[2])},
-				&Tag{StartName: "R10", NoIndent: true, Text: encodeFloat(value.Rotation[3])},
-				&Tag{StartName: "R11", NoIndent: true, Text: encodeFloat(value.Rotation[4])},
-				&Tag{StartName: "R12", NoIndent: true, Text: encodeFloat(value.Rotation[5])},
-				&Tag{StartName: "R20", NoIndent: true, Text: encodeFloat(value.Rotation[6])},
-				&Tag{StartName: "R21", NoIndent: true, Text: encodeFloat(value.Rotation[7])},
-				&Tag{StartName: "R22", NoIndent: true, Text: encodeFloat(value.Rotation[8])},
+				&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.Position.X)},
+				&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Position.Y)},
+				&Tag{StartName: "Z", NoIndent: true, Text: enc.encodeFloat(value.Position.Z)},
+				&Tag{StartName: "R00", NoIndent: true, Text: enc.encodeFloat(value.Rotation[0])},
+				&Tag{StartName: "R01", NoIndent: true, Text: enc.encodeFloat(value.Rotation[1])},
+				&Tag{StartName: "R02", NoIndent: true, Text: enc.encodeFloat(value.Rotation[2])},
+				&Tag{StartName: "R10", NoIndent: true, Text: enc.encodeFloat(value.Rotation[3])},
+				&Tag{StartName: "R11", NoIndent: true, Text: enc.encodeFloat(value.Rotation[4])},
+				&Tag{StartName: "R12", NoIndent: true, Text: enc.encodeFloat(value.Rotation[5])},
+				&Tag{StartName: "R20", NoIndent: true, Text: enc.encodeFloat(value.Rotation[6])},
+				&Tag{StartName: "R21", NoIndent: true, Text: enc.encodeFloat(value.Rotation[7])},
+				&Tag{StartName: "R22", NoIndent: true, Text: enc.encodeFloat(value.Rotation[8])},
 			},
 		}
 
@@ -1156,12 +1551,40 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 		}
 		return tag
 
+	case rbxfile.ValueContentData:
+		tag := &Tag{
+			StartName: "Content",
+			Attr:      attr,
+			NoIndent:  true,
+		}
+		if value.Hash != "" || value.Data == nil {
+			tag.Tags = append(tag.Tags, &Tag{
+				StartName: "hash",
+				NoIndent:  true,
+				Text:      value.Hash,
+			})
+		}
+		if value.Data != nil {
+			buf := new(bytes.Buffer)
+			sw := &lineSplit{w: buf, s: 72, n: 72}
+			bw := base64.NewEncoder(base64.StdEncoding, sw)
+			bw.Write(value.Data)
+			bw.Close()
+			subtag := &Tag{
+				StartName: "binary",
+				NoIndent:  true,
+			}
+			encodeContent(subtag, buf.String())
+			tag.Tags = append(tag.Tags, subtag)
+		}
+		return tag
+
 	case rbxfile.ValueDouble:
 		return &Tag{
 			StartName: "double",
 			Attr:      attr,
 			NoIndent:  true,
-			Text:      encodeDouble(float64(value)),
+			Text:      enc.encodeDouble(float64(value)),
 		}
 
 	case rbxfile.ValueFaces:
@@ -1188,7 +1611,7 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "float",
 			Attr:      attr,
 			NoIndent:  true,
-			Text:      encodeFloat(float32(value)),
+			Text:      enc.encodeFloat(float32(value)),
 		}
 
 	case rbxfile.ValueInt:
@@ -1216,17 +1639,17 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 				&Tag{
 					StartName: "origin",
 					Tags: []*Tag{
-						&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.Origin.X)},
-						&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Origin.Y)},
-						&Tag{StartName: "Z", NoIndent: true, Text: encodeFloat(value.Origin.Z)},
+						&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.Origin.X)},
+						&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Origin.Y)},
+						&Tag{StartName: "Z", NoIndent: true, Text: enc.encodeFloat(value.Origin.Z)},
 					},
 				},
 				&Tag{
 					StartName: "direction",
 					Tags: []*Tag{
-						&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.Origin.X)},
-						&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Origin.Y)},
-						&Tag{StartName: "Z", NoIndent: true, Text: encodeFloat(value.Origin.Z)},
+						&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.Origin.X)},
+						&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Origin.Y)},
+						&Tag{StartName: "Z", NoIndent: true, Text: enc.encodeFloat(value.Origin.Z)},
 					},
 				},
 			},
@@ -1241,7 +1664,7 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 
 		referent := value.Instance
 		if referent != nil {
-			tag.Text = enc.refs.Get(referent)
+			tag.Text = enc.reference(referent)
 		} else {
 			tag.Text = "null"
 		}
@@ -1268,7 +1691,7 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "UDim",
 			Attr:      attr,
 			Tags: []*Tag{
-				&Tag{StartName: "S", NoIndent: true, Text: encodeFloat(value.Scale)},
+				&Tag{StartName: "S", NoIndent: true, Text: enc.encodeFloat(value.Scale)},
 				&Tag{StartName: "O", NoIndent: true, Text: strconv.FormatInt(int64(value.Offset), 10)},
 			},
 		}
@@ -1278,9 +1701,9 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "UDim2",
 			Attr:      attr,
 			Tags: []*Tag{
-				&Tag{StartName: "XS", NoIndent: true, Text: encodeFloat(value.X.Scale)},
+				&Tag{StartName: "XS", NoIndent: true, Text: enc.encodeFloat(value.X.Scale)},
 				&Tag{StartName: "XO", NoIndent: true, Text: strconv.FormatInt(int64(value.X.Offset), 10)},
-				&Tag{StartName: "YS", NoIndent: true, Text: encodeFloat(value.Y.Scale)},
+				&Tag{StartName: "YS", NoIndent: true, Text: enc.encodeFloat(value.Y.Scale)},
 				&Tag{StartName: "YO", NoIndent: true, Text: strconv.FormatInt(int64(value.Y.Offset), 10)},
 			},
 		}
@@ -1290,8 +1713,8 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "Vector2",
 			Attr:      attr,
 			Tags: []*Tag{
-				&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.X)},
-				&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Y)},
+				&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.X)},
+				&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Y)},
 			},
 		}
 
@@ -1310,9 +1733,9 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 			StartName: "Vector3",
 			Attr:      attr,
 			Tags: []*Tag{
-				&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.X)},
-				&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Y)},
-				&Tag{StartName: "Z", NoIndent: true, Text: encodeFloat(value.Z)},
+				&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.X)},
+				&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Y)},
+				&Tag{StartName: "Z", NoIndent: true, Text: enc.encodeFloat(value.Z)},
 			},
 		}
 
@@ -1330,11 +1753,11 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 	case rbxfile.ValueNumberSequence:
 		b := make([]byte, 0, 16)
 		for _, nsk := range value {
-			b = append(b, []byte(encodeFloatPrec(nsk.Time, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(nsk.Time, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(nsk.Value, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(nsk.Value, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(nsk.Envelope, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(nsk.Envelope, 6))...)
 			b = append(b, ' ')
 		}
 		return &Tag{
@@ -1346,15 +1769,15 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 	case rbxfile.ValueColorSequence:
 		b := make([]byte, 0, 32)
 		for _, csk := range value {
-			b = append(b, []byte(encodeFloatPrec(csk.Time, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(csk.Time, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(csk.Value.R, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(csk.Value.R, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(csk.Value.G, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(csk.Value.G, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(csk.Value.B, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(csk.Value.B, 6))...)
 			b = append(b, ' ')
-			b = append(b, []byte(encodeFloatPrec(csk.Envelope, 6))...)
+			b = append(b, []byte(enc.encodeFloatPrec(csk.Envelope, 6))...)
 			b = append(b, ' ')
 		}
 		return &Tag{
@@ -1365,9 +1788,9 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 
 	case rbxfile.ValueNumberRange:
 		b := make([]byte, 0, 8)
-		b = append(b, []byte(encodeFloatPrec(value.Min, 6))...)
+		b = append(b, []byte(enc.encodeFloatPrec(value.Min, 6))...)
 		b = append(b, ' ')
-		b = append(b, []byte(encodeFloatPrec(value.Max, 6))...)
+		b = append(b, []byte(enc.encodeFloatPrec(value.Max, 6))...)
 		b = append(b, ' ')
 		return &Tag{
 			StartName: "NumberRange",
@@ -1383,15 +1806,15 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 				&Tag{
 					StartName: "min",
 					Tags: []*Tag{
-						&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.Min.X)},
-						&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Min.Y)},
+						&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.Min.X)},
+						&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Min.Y)},
 					},
 				},
 				&Tag{
 					StartName: "max",
 					Tags: []*Tag{
-						&Tag{StartName: "X", NoIndent: true, Text: encodeFloat(value.Max.X)},
-						&Tag{StartName: "Y", NoIndent: true, Text: encodeFloat(value.Max.Y)},
+						&Tag{StartName: "X", NoIndent: true, Text: enc.encodeFloat(value.Max.X)},
+						&Tag{StartName: "Y", NoIndent: true, Text: enc.encodeFloat(value.Max.Y)},
 					},
 				},
 			},
@@ -1404,11 +1827,11 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 				Attr:      attr,
 				Tags: []*Tag{
 					&Tag{StartName: "CustomPhysics", Text: "true"},
-					&Tag{StartName: "Density", Text: encodeFloat(value.Density)},
-					&Tag{StartName: "Friction", Text: encodeFloat(value.Friction)},
-					&Tag{StartName: "Elasticity", Text: encodeFloat(value.Elasticity)},
-					&Tag{StartName: "FrictionWeight", Text: encodeFloat(value.FrictionWeight)},
-					&Tag{StartName: "ElasticityWeight", Text: encodeFloat(value.ElasticityWeight)},
+					&Tag{StartName: "Density", Text: enc.encodeFloat(value.Density)},
+					&Tag{StartName: "Friction", Text: enc.encodeFloat(value.Friction)},
+					&Tag{StartName: "Elasticity", Text: enc.encodeFloat(value.Elasticity)},
+					&Tag{StartName: "FrictionWeight", Text: enc.encodeFloat(value.FrictionWeight)},
+					&Tag{StartName: "ElasticityWeight", Text: enc.encodeFloat(value.ElasticityWeight)},
 				},
 			}
 		} else {
@@ -1441,6 +1864,18 @@ func (enc *rencoder) encodeProperty(class, prop string, value rbxfile.Value) *Ta
 		}
 
 	case rbxfile.ValueSharedString:
+		if enc.codec.Canonical {
+			// The value is written to the SharedStrings tag, and is referred
+			// to by its hash.
+			hash := md5.Sum([]byte(value))
+			enc.sharedStrings[string(hash[:])] = []byte(value)
+			return &Tag{
+				StartName: "SharedString",
+				Attr:      attr,
+				NoIndent:  true,
+				Text:      base64.StdEncoding.EncodeToString(hash[:]),
+			}
+		}
 		buf := new(bytes.Buffer)
 		sw := &lineSplit{w: buf, s: 72, n: 72}
 		bw := base64.NewEncoder(base64.StdEncoding, sw)
@@ -1515,6 +1950,33 @@ func encodeDouble(f float64) string {
 	return strconv.FormatFloat(f, 'g', 9, 64)
 }
 
+// encodeFloat is like encodeFloat, but uses the shortest representation if
+// the codec is canonical.
+func (enc *rencoder) encodeFloat(f float32) string {
+	if enc.codec.Canonical {
+		return encodeFloatPrec(f, -1)
+	}
+	return encodeFloat(f)
+}
+
+// encodeFloatPrec is like encodeFloatPrec, but uses the shortest
+// representation if the codec is canonical.
+func (enc *rencoder) encodeFloatPrec(f float32, prec int) string {
+	if enc.codec.Canonical {
+		prec = -1
+	}
+	return encodeFloatPrec(f, prec)
+}
+
+// encodeDouble is like encodeDouble, but uses the shortest representation if
+// the codec is canonical.
+func (enc *rencoder) encodeDouble(f float64) string {
+	if enc.codec.Canonical {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return encodeDouble(f)
+}
+
 func encodeContent(tag *Tag, text string) {
 	if len(text) > 0 && strings.Index(text, "]]>") == -1 {
 		tag.CData = []byte(text)
@@ -1539,6 +2001,8 @@ func isCanonType(t string, v rbxfile.Value) bool {
 		return t == "Color3"
 	case rbxfile.ValueContent:
 		return t == "Content"
+	case rbxfile.ValueContentData:
+		return t == "Content"
 	case rbxfile.ValueDouble:
 		return t == "double"
 	case rbxfile.ValueFaces:
@@ -0,0 +1,375 @@
+package xml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+)
+
+func codecTestRoot() *rbxfile.Root {
+	model := rbxfile.NewInstance("Model", nil)
+	model.Set("Name", rbxfile.ValueString("Model"))
+	part := rbxfile.NewInstance("Part", model)
+	part.Set("Name", rbxfile.ValueString("Part"))
+	part.Set("Transparency", rbxfile.ValueFloat(0.1))
+	part.Set("Size", rbxfile.ValueVector3{X: 0.2, Y: 1, Z: 4})
+	part.Set("PhysicsData", rbxfile.ValueSharedString("shared data"))
+	mesh := rbxfile.NewInstance("MeshPart", model)
+	mesh.Set("PhysicsData", rbxfile.ValueSharedString("shared data"))
+	// Refers to an instance later in the tree.
+	model.Set("PrimaryPart", rbxfile.ValueReference{Instance: mesh})
+	return &rbxfile.Root{
+		Instances: []*rbxfile.Instance{model},
+		Metadata:  map[string]string{"B": "2", "A": "1"},
+	}
+}
+
+func encodeTest(t *testing.T, codec RobloxCodec, root *rbxfile.Root) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := (Serializer{Decoder: codec, Encoder: codec}).Serialize(&buf, root); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	return buf.String()
+}
+
+func TestCanonical(t *testing.T) {
+	codec := RobloxCodec{Canonical: true}
+	a := encodeTest(t, codec, codecTestRoot())
+	b := encodeTest(t, codec, codecTestRoot())
+	if a != b {
+		t.Fatal("expected identical output")
+	}
+	for _, s := range []string{
+		`<Item class="Model" referent="RBX0">`,
+		`<Item class="Part" referent="RBX1">`,
+		`<Item class="MeshPart" referent="RBX2">`,
+		`<Ref name="PrimaryPart">RBX2</Ref>`,
+		`<float name="Transparency">0.1</float>`,
+		`<X>0.2</X>`,
+	} {
+		if !strings.Contains(a, s) {
+			t.Errorf("expected output to contain %s", s)
+		}
+	}
+	if strings.Count(a, "<SharedString md5=") != 1 {
+		t.Error("expected one shared string")
+	}
+	if i, j := strings.Index(a, `name="A"`), strings.Index(a, `name="B"`); i < 0 || i > j {
+		t.Error("expected sorted metadata")
+	}
+
+	if a := encodeTest(t, RobloxCodec{}, codecTestRoot()); !strings.Contains(a, "0.100000001") {
+		t.Error("expected fixed precision when not canonical")
+	}
+}
+
+func TestDecodeReferences(t *testing.T) {
+	const doc = `<roblox version="4">
+	<Item class="Model" referent="RBXA">
+		<Properties><Ref name="PrimaryPart">RBXB</Ref></Properties>
+		<Item class="Part" referent="RBXB"></Item>
+		<Item class="Part"></Item>
+		<Item class="Part" referent="RBXB"></Item>
+	</Item>
+</roblox>`
+
+	gen := &rbxfile.SequentialReferences{}
+	codec := RobloxCodec{References: gen}
+	root, err := Serializer{Decoder: codec, Encoder: codec}.Deserialize(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	model := root.Instances[0]
+	if len(model.Children) != 3 {
+		t.Fatalf("expected 3 children, got %d", len(model.Children))
+	}
+	for i, want := range []string{"RBXA", "RBXB", "RBX0", "RBX1"} {
+		inst := model
+		if i > 0 {
+			inst = model.Children[i-1]
+		}
+		if inst.Reference != want {
+			t.Errorf("instance %d: expected reference %s, got %s", i, want, inst.Reference)
+		}
+	}
+	if gen.Next != 2 {
+		t.Errorf("expected 2 generated references, got %d", gen.Next)
+	}
+	if v, _ := model.Get("PrimaryPart").(rbxfile.ValueReference); v.Instance != model.Children[0] {
+		t.Error("expected reference to resolve to first instance with referent")
+	}
+}
+
+func TestSharedStrings(t *testing.T) {
+	inline := encodeTest(t, RobloxCodec{}, codecTestRoot())
+	if strings.Contains(inline, "<SharedStrings>") {
+		t.Error("expected no SharedStrings tag when not canonical")
+	}
+	if strings.Count(inline, `<SharedString name="PhysicsData"><![CDATA[c2hhcmVkIGRhdGE=]]></SharedString>`) != 2 {
+		t.Error("expected inline shared strings when not canonical")
+	}
+	canonical := encodeTest(t, RobloxCodec{Canonical: true}, codecTestRoot())
+	for _, s := range []string{inline, canonical} {
+		root, err := Deserialize(strings.NewReader(s), nil)
+		if err != nil {
+			t.Fatalf("deserialize: %s", err)
+		}
+		model := root.Instances[0]
+		for _, inst := range model.Children {
+			if v, _ := inst.Get("PhysicsData").(rbxfile.ValueSharedString); string(v) != "shared data" {
+				t.Errorf("%s: unexpected shared string %v", inst.ClassName, v)
+			}
+		}
+	}
+}
+
+const unknownTestDocument = `<roblox version="4" custom="root">
+	<Future>data</Future>
+	<Item class="Part" referent="RBX0" custom="item">
+		<Properties>
+			<string name="Name">Part</string>
+			<FutureType name="Future"><A>1</A></FutureType>
+		</Properties>
+		<Extension>value</Extension>
+		<Item class="Folder" referent="RBX1">
+			<Properties>
+				<string name="Name">Folder</string>
+			</Properties>
+		</Item>
+	</Item>
+</roblox>`
+
+func TestPreserveUnknown(t *testing.T) {
+	codec := RobloxCodec{PreserveUnknown: true}
+	s := Serializer{Decoder: codec, Encoder: codec}
+	root, err := s.Deserialize(strings.NewReader(unknownTestDocument))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	part := root.Instances[0]
+	unknown, ok := part.Extra.(*Unknown)
+	if !ok {
+		t.Fatal("expected unknown content on Part")
+	}
+	if len(unknown.Attr) != 1 || len(unknown.Tags) != 1 || len(unknown.Properties) != 1 {
+		t.Errorf("unexpected unknown content %+v", unknown)
+	}
+	if part.Children[0].Extra != nil {
+		t.Error("expected no unknown content on Folder")
+	}
+	if _, ok := root.Extra.(*Unknown); !ok {
+		t.Error("expected unknown content on Root")
+	}
+
+	out := encodeTest(t, codec, root)
+	for _, want := range []string{
+		`custom="root"`,
+		`<Future>data</Future>`,
+		`custom="item"`,
+		`<Extension>value</Extension>`,
+		`<FutureType name="Future">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %s", want)
+		}
+	}
+	if out := encodeTest(t, RobloxCodec{}, root); strings.Contains(out, "Future") {
+		t.Error("expected unknown content to be written only when preserving")
+	}
+
+	root, err = Deserialize(strings.NewReader(unknownTestDocument), nil)
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if root.Extra != nil || root.Instances[0].Extra != nil {
+		t.Error("expected unknown content to be discarded by default")
+	}
+}
+
+const contentDataTestDocument = `<roblox version="4">
+	<Item class="Decal" referent="RBX0">
+		<Properties>
+			<Content name="Texture"><binary>ZW1iZWRkZWQgaW1hZ2U=</binary></Content>
+			<Content name="Hashed"><hash>abc123</hash></Content>
+			<Content name="Both"><hash>def456</hash><binary>ZGF0YQ==</binary></Content>
+		</Properties>
+	</Item>
+</roblox>`
+
+func TestContentData(t *testing.T) {
+	root, err := Deserialize(strings.NewReader(contentDataTestDocument), nil)
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	want := map[string]rbxfile.ValueContentData{
+		"Texture": {Data: []byte("embedded image")},
+		"Hashed":  {Hash: "abc123"},
+		"Both":    {Hash: "def456", Data: []byte("data")},
+	}
+	check := func(root *rbxfile.Root) {
+		t.Helper()
+		decal := root.Instances[0]
+		for name, w := range want {
+			v, ok := decal.Get(name).(rbxfile.ValueContentData)
+			if !ok {
+				t.Errorf("%s: expected ContentData, got %T", name, decal.Get(name))
+				continue
+			}
+			if v.Hash != w.Hash || string(v.Data) != string(w.Data) || (v.Data == nil) != (w.Data == nil) {
+				t.Errorf("%s: expected %+v, got %+v", name, w, v)
+			}
+		}
+	}
+	check(root)
+
+	out := encodeTest(t, RobloxCodec{}, root)
+	if !strings.Contains(out, "<binary><![CDATA[ZW1iZWRkZWQgaW1hZ2U=]]></binary>") {
+		t.Error("expected binary data in output")
+	}
+	if root, err = Deserialize(strings.NewReader(out), nil); err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	check(root)
+}
+
+func TestDecodeStream(t *testing.T) {
+	root := codecTestRoot()
+	folder := rbxfile.NewInstance("Folder", nil)
+	folder.Set("Name", rbxfile.ValueString("Folder"))
+	rbxfile.NewInstance("Script", folder).Set("Name", rbxfile.ValueString("Script"))
+	root.Instances = append(root.Instances, folder)
+	doc := encodeTest(t, RobloxCodec{}, root)
+
+	codec := RobloxCodec{}
+	s := Serializer{Decoder: codec, Encoder: codec}
+	var order []string
+	streamed, err := s.DeserializeStream(strings.NewReader(doc), Stream{
+		Instance: func(inst *rbxfile.Instance) error {
+			if inst.Parent() != nil {
+				t.Errorf("%s: expected no parent", inst.ClassName)
+			}
+			order = append(order, inst.ClassName)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if want := "Part MeshPart Model Script Folder"; strings.Join(order, " ") != want {
+		t.Errorf("expected order %q, got %q", want, strings.Join(order, " "))
+	}
+	tree, err := s.Deserialize(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	canon := RobloxCodec{Canonical: true}
+	if encodeTest(t, canon, streamed) != encodeTest(t, canon, tree) {
+		t.Error("expected streamed root to match tree root")
+	}
+	if v := streamed.Instances[0].Get("PrimaryPart").(rbxfile.ValueReference); v.Instance != streamed.Instances[0].Children[1] {
+		t.Error("expected reference to be resolved")
+	}
+
+	filtered, err := s.DeserializeStream(strings.NewReader(doc), Stream{
+		Filter: func(className string) bool { return className != "Model" && className != "Folder" },
+	})
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	var classes []string
+	for _, inst := range filtered.Instances {
+		classes = append(classes, inst.ClassName)
+	}
+	if want := "Part MeshPart Script"; strings.Join(classes, " ") != want {
+		t.Errorf("expected instances %q, got %q", want, strings.Join(classes, " "))
+	}
+	if v, _ := filtered.Instances[0].Get("PhysicsData").(rbxfile.ValueSharedString); string(v) != "shared data" {
+		t.Error("expected shared string to be resolved")
+	}
+
+	stop := errors.New("stop")
+	_, err = s.DeserializeStream(strings.NewReader(doc), Stream{
+		Instance: func(*rbxfile.Instance) error { return stop },
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected stop error, got %v", err)
+	}
+}
+
+func TestEncodeStream(t *testing.T) {
+	s := Serializer{Decoder: RobloxCodec{}, Encoder: RobloxCodec{}}
+	unknown, err := s.Deserialize(strings.NewReader(unknownTestDocument))
+	if err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	for name, root := range map[string]*rbxfile.Root{
+		"codec":   codecTestRoot(),
+		"unknown": unknown,
+		"empty":   &rbxfile.Root{},
+	} {
+		for _, codec := range []RobloxCodec{
+			{Canonical: true},
+			{Canonical: true, PreserveUnknown: true, ExcludeExternal: true, ExcludeMetadata: true},
+		} {
+			s := Serializer{Encoder: codec}
+			want := encodeTest(t, codec, root)
+			var buf bytes.Buffer
+			if err := s.SerializeStream(&buf, root); err != nil {
+				t.Fatalf("%s: serialize: %s", name, err)
+			}
+			if buf.String() != want {
+				t.Errorf("%s: expected output to match Serialize\n%s\n%s", name, want, buf.String())
+			}
+
+			doc, err := codec.Encode(root)
+			if err != nil {
+				t.Fatalf("%s: encode: %s", name, err)
+			}
+			format := Document{Prefix: "  ", Indent: "    ", Suffix: "\n", ExcludeRoot: true}
+			doc.Prefix, doc.Indent, doc.Suffix, doc.ExcludeRoot = format.Prefix, format.Indent, format.Suffix, format.ExcludeRoot
+			var a, b bytes.Buffer
+			doc.WriteTo(&a)
+			if err := codec.EncodeStream(&b, &format, root); err != nil {
+				t.Fatalf("%s: encode stream: %s", name, err)
+			}
+			if a.String() != b.String() {
+				t.Errorf("%s: expected formatted output to match WriteTo\n%s\n%s", name, a.String(), b.String())
+			}
+		}
+	}
+}
+
+func TestSerializeContext(t *testing.T) {
+	s := NewSerializer(nil, nil)
+	var buf bytes.Buffer
+	var last rbxfile.Progress
+	if err := s.SerializeContext(context.Background(), &buf, codecTestRoot(), func(p rbxfile.Progress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("serialize: %s", err)
+	}
+	if last.Bytes != int64(buf.Len()) {
+		t.Errorf("expected %d bytes, got %d", buf.Len(), last.Bytes)
+	}
+
+	last = rbxfile.Progress{}
+	if _, err := s.DeserializeContext(context.Background(), bytes.NewReader(buf.Bytes()), func(p rbxfile.Progress) {
+		last = p
+	}); err != nil {
+		t.Fatalf("deserialize: %s", err)
+	}
+	if last.Instances != 3 {
+		t.Errorf("expected 3 instances, got %d", last.Instances)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.SerializeContext(ctx, &buf, codecTestRoot(), nil); err != context.Canceled {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
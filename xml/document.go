@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"github.com/robloxapi/rbxfile"
 	"io"
 	"strconv"
 )
@@ -202,6 +203,13 @@ type Document struct {
 	// be cleared and populated when calling either ReadFrom and WriteTo.
 	// Codecs may also clear and populate this when decoding or encoding.
 	Warnings []error
+
+	// Limits bounds the resources used by ReadFrom. MaxTotalBytes limits the
+	// length of the document, MaxStringLength limits the length of names,
+	// attribute values, and text, and MaxDepth limits the nesting of tags.
+	// When a limit is exceeded, ReadFrom returns a rbxfile.ErrLimit. Codecs
+	// may also honor Limits when decoding.
+	Limits rbxfile.Limits
 }
 
 // A SyntaxError represents a syntax error in the XML input stream.
@@ -222,6 +230,8 @@ type decoder struct {
 	n        int64
 	err      error
 	line     int
+	depth    int
+	tagFunc  func(parent, tag *Tag) (keep bool, err error)
 }
 
 // Creates a SyntaxError with the current line number.
@@ -416,6 +426,12 @@ func (d *decoder) decodeTag(root bool) (tag *Tag, err error) {
 		return nil, d.err
 	}
 
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.err = d.doc.Limits.Check(rbxfile.LimitDepth, int64(d.depth)); d.err != nil {
+		return nil, d.err
+	}
+
 	tag = new(Tag)
 	noindent := false
 	nocontent := true
@@ -443,6 +459,8 @@ func (d *decoder) decodeTag(root bool) (tag *Tag, err error) {
 			d.err = d.syntaxError("no roblox tag")
 			return nil, d.err
 		}
+		// Allows tagFunc to recognize the root tag.
+		d.doc.Root = tag
 
 		if v, ok := tag.AttrValue("version"); !ok {
 			//DIFF: returns success, but no data is read
@@ -548,6 +566,16 @@ func (d *decoder) decodeTag(root bool) (tag *Tag, err error) {
 			return nil, err
 		}
 		if subtag != nil {
+			if d.tagFunc != nil {
+				keep, err := d.tagFunc(tag, subtag)
+				if err != nil {
+					d.err = err
+					return nil, err
+				}
+				if !keep {
+					continue
+				}
+			}
 			tag.Tags = append(tag.Tags, subtag)
 		}
 	}
@@ -638,6 +666,10 @@ func (d *decoder) getc() (b byte, ok bool) {
 			return 0, false
 		}
 		d.n++
+		if max := d.doc.Limits.MaxTotalBytes; max > 0 && d.n > max {
+			d.err = rbxfile.ErrLimit{Limit: rbxfile.LimitTotalBytes, Max: max, Value: d.n}
+			return 0, false
+		}
 	}
 	if b == '\n' {
 		d.line++
@@ -700,6 +732,9 @@ func (d *decoder) text(quote int, cdata bool) []byte {
 	d.buf.Reset()
 Input:
 	for {
+		if !d.checkLength() {
+			return nil
+		}
 		b, ok := d.getc()
 		if !ok {
 			if cdata {
@@ -809,7 +844,7 @@ Input:
 
 					s := string(name)
 					if r, ok := entity[s]; ok {
-						text = string(r)
+						text = string(rune(r))
 						haveText = true
 					}
 				}
@@ -837,6 +872,9 @@ Input:
 
 		b0, b1 = b1, b
 	}
+	if !d.checkLength() {
+		return nil
+	}
 	buf := d.buf.Bytes()
 	buf = buf[0 : len(buf)-trunc]
 
@@ -881,6 +919,19 @@ func (d *decoder) readName(typ int) (ok bool) {
 			break
 		}
 		d.buf.WriteByte(b)
+		if !d.checkLength() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLength sets d.err and returns false if the content of d.buf exceeds
+// the string length limit.
+func (d *decoder) checkLength() bool {
+	if max := d.doc.Limits.MaxStringLength; max > 0 && d.buf.Len() > max {
+		d.err = rbxfile.ErrLimit{Limit: rbxfile.LimitStringLength, Max: int64(max), Value: int64(d.buf.Len())}
+		return false
 	}
 	return true
 }
@@ -908,6 +959,15 @@ func isNameByte(c byte, t int) bool {
 
 // ReadFrom decode data from r into the Document.
 func (doc *Document) ReadFrom(r io.Reader) (n int64, err error) {
+	return doc.ReadFromFunc(r, nil)
+}
+
+// ReadFromFunc is like ReadFrom, but calls fn as each tag below the root tag
+// is closed, with the tag and the tag that contains it. The Root field is set
+// before the first call. The tag is added to its parent only if keep is true,
+// which allows large documents to be processed without retaining every tag.
+// If fn returns an error, then reading stops, and the error is returned.
+func (doc *Document) ReadFromFunc(r io.Reader, fn func(parent, tag *Tag) (keep bool, err error)) (n int64, err error) {
 	if r == nil {
 		return 0, errors.New("reader is nil")
 	}
@@ -920,6 +980,7 @@ func (doc *Document) ReadFrom(r io.Reader) (n int64, err error) {
 		doc:      doc,
 		nextByte: make([]byte, 0, 9),
 		line:     1,
+		tagFunc:  fn,
 	}
 	if rb, ok := r.(io.ByteReader); ok {
 		d.r = rb
@@ -990,20 +1051,55 @@ func (e *encoder) checkName(name string, typ int) bool {
 }
 
 func (e *encoder) encodeTag(tag *Tag, noTags bool, noindent bool) int {
+	t, r := e.startTag(tag, noTags, noindent)
+	if r <= 0 {
+		return r
+	}
+	if !t.empty {
+		for _, sub := range tag.Tags {
+			if t.child(sub) < 0 {
+				return -1
+			}
+		}
+	}
+	return t.end()
+}
+
+// tagWriter encodes a tag incrementally, so that its child tags do not need
+// to be known in advance.
+type tagWriter struct {
+	e        *encoder
+	tag      *Tag
+	endName  string
+	noTags   bool
+	noindent bool
+	empty    bool
+	n        int
+}
+
+// startTag writes the start tag of tag, returning a tagWriter used to write
+// the remainder. Returns -1 if an error occurred, 0 if the tag was skipped,
+// and 1 otherwise.
+func (e *encoder) startTag(tag *Tag, noTags bool, noindent bool) (t *tagWriter, r int) {
 	if e.err != nil {
-		return -1
+		return nil, -1
 	}
 
-	endName := tag.EndName
+	t = &tagWriter{
+		e:        e,
+		tag:      tag,
+		endName:  tag.EndName,
+		noTags:   noTags,
+		noindent: noindent || tag.NoIndent,
+	}
 
 	if !noTags {
-		if !e.checkName(tag.StartName, nameTag) {
-			e.d.Warnings = append(e.d.Warnings, errors.New("ignored tag with malformed start name `"+tag.StartName+"`"))
-			return 0
+		if !e.checkStartName(tag) {
+			return nil, 0
 		}
 
-		if !e.checkName(endName, nameTag) && endName != "" {
-			endName = tag.StartName
+		if !e.checkName(t.endName, nameTag) && t.endName != "" {
+			t.endName = tag.StartName
 			e.d.Warnings = append(e.d.Warnings, errors.New("tag with malformed end name `"+tag.EndName+"`, used start name instead"))
 		}
 
@@ -1027,61 +1123,105 @@ func (e *encoder) encodeTag(tag *Tag, noTags bool, noindent bool) int {
 			e.writeByte('/')
 			e.writeByte('>')
 			if !e.flush() {
-				return -1
+				return nil, -1
 			}
-			return 1
+			t.empty = true
+			return t, 1
 		}
 
 		e.writeByte('>')
 		if !e.flush() {
-			return -1
+			return nil, -1
 		}
 	}
 
 	if !e.encodeCData(tag) {
-		return -1
+		return nil, -1
+	}
+
+	return t, 1
+}
+
+// checkStartName returns whether the start name of tag is well-formed. If not,
+// a warning is emitted, and the tag is skipped.
+func (e *encoder) checkStartName(tag *Tag) bool {
+	if !e.checkName(tag.StartName, nameTag) {
+		e.d.Warnings = append(e.d.Warnings, errors.New("ignored tag with malformed start name `"+tag.StartName+"`"))
+		return false
 	}
+	return true
+}
 
-	if !noindent && !tag.NoIndent {
-		if len(tag.Tags) > 0 {
-			if noTags {
-				e.writeIndent(0, true)
+// next writes the content that precedes the next child tag. It is called only
+// once the child is known to produce output.
+func (t *tagWriter) next() bool {
+	if t.n == 0 {
+		if !t.noindent {
+			if t.noTags {
+				t.e.writeIndent(0, true)
 			} else {
-				e.writeIndent(1, false)
+				t.e.writeIndent(1, false)
 			}
 		}
+		if !t.e.encodeText(t.tag) {
+			return false
+		}
+	} else if !t.noindent {
+		t.e.writeIndent(0, false)
 	}
+	t.n++
+	return true
+}
 
-	if !e.encodeText(tag) {
+// child encodes sub as the next child tag.
+func (t *tagWriter) child(sub *Tag) int {
+	if !t.e.checkStartName(sub) {
+		return 0
+	}
+	if !t.next() {
 		return -1
 	}
+	return t.e.encodeTag(sub, false, t.noindent)
+}
+
+// startChild starts sub as the next child tag, returning a tagWriter used to
+// write the remainder of sub. The child tags of sub are not written.
+func (t *tagWriter) startChild(sub *Tag) (*tagWriter, int) {
+	if !t.e.checkStartName(sub) {
+		return nil, 0
+	}
+	if !t.next() {
+		return nil, -1
+	}
+	return t.e.startTag(sub, false, t.noindent)
+}
 
-	for i, sub := range tag.Tags {
-		if r := e.encodeTag(sub, false, noindent || tag.NoIndent); r < 0 {
+// end writes the end of the tag.
+func (t *tagWriter) end() int {
+	if t.empty {
+		return 1
+	}
+
+	e := t.e
+	if t.n == 0 {
+		if !e.encodeText(t.tag) {
 			return -1
-		} else if r == 0 {
-			continue
 		}
-		if !noindent && !tag.NoIndent {
-			if i == len(tag.Tags)-1 {
-				if noTags {
-					e.writeIndent(0, true)
-				} else {
-					e.writeIndent(-1, false)
-				}
-			} else {
-				e.writeIndent(0, false)
-			}
+	} else if !t.noindent {
+		if t.noTags {
+			e.writeIndent(0, true)
+		} else {
+			e.writeIndent(-1, false)
 		}
 	}
 
-	if !noTags {
+	if !t.noTags {
 		e.writeByte('<')
 		e.writeByte('/')
-		if endName == "" {
-			e.writeString(tag.StartName)
+		if t.endName == "" {
+			e.writeString(t.tag.StartName)
 		} else {
-			e.writeString(endName)
+			e.writeString(t.endName)
 		}
 		e.writeByte('>')
 
@@ -1244,3 +1384,29 @@ func (d *Document) WriteTo(w io.Writer) (n int64, err error) {
 	e.flush()
 	return e.n, e.err
 }
+
+// writeStart begins writing the Document to w incrementally, with root as
+// the root tag, ignoring the Root field. The child tags of root are not
+// written. The returned tagWriter is used to write the content of the root
+// tag, after which writeEnd completes the document.
+func (d *Document) writeStart(w io.Writer, root *Tag) (e *encoder, t *tagWriter) {
+	d.Warnings = d.Warnings[:0]
+
+	e = &encoder{Writer: bufio.NewWriter(w), d: d}
+
+	e.writeString(e.d.Prefix)
+
+	t, _ = e.startTag(root, d.ExcludeRoot, root.NoIndent)
+	return e, t
+}
+
+// writeEnd finishes writing a Document started by writeStart.
+func (e *encoder) writeEnd(t *tagWriter) (n int64, err error) {
+	if t == nil || t.end() < 0 {
+		return e.n, e.err
+	}
+
+	e.writeString(e.d.Suffix)
+	e.flush()
+	return e.n, e.err
+}
@@ -0,0 +1,37 @@
+package xml
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDocumentSkippedTag(t *testing.T) {
+	doc := &Document{
+		Indent: "\t",
+		Root: &Tag{StartName: "roblox", Tags: []*Tag{
+			{StartName: "A", Text: "x", NoIndent: true},
+			{StartName: "bad name"},
+			{StartName: "B", Text: "y", NoIndent: true},
+			{StartName: ""},
+		}},
+	}
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<roblox>\n\t<A>x</A>\n\t<B>y</B>\n</roblox>"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	if len(doc.Warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %d", len(doc.Warnings))
+	}
+
+	doc.Root.Tags = doc.Root.Tags[3:]
+	buf.Reset()
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<roblox></roblox>"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
@@ -1,7 +1,9 @@
 package xml
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/robloxapi/rbxapi"
 	"github.com/robloxapi/rbxfile"
 	"io"
@@ -17,11 +19,44 @@ type Encoder interface {
 	Encode(root *rbxfile.Root) (document *Document, err error)
 }
 
+// StreamDecoder decodes a document as it is read from a byte stream.
+type StreamDecoder interface {
+	DecodeStream(document *Document, r io.Reader, stream Stream) (root *rbxfile.Root, err error)
+}
+
+// StreamEncoder encodes a rbxfile.Root structure directly to a byte stream.
+type StreamEncoder interface {
+	EncodeStream(w io.Writer, document *Document, root *rbxfile.Root) (err error)
+}
+
+// Stream configures the decoding of a document by a StreamDecoder.
+type Stream struct {
+	// Filter selects which instances are decoded. If not nil, then an Item is
+	// decoded only if Filter returns true for its class name. The properties
+	// of unselected Items are not decoded. Selected descendants of an
+	// unselected Item are added to the nearest selected ancestor instead, or
+	// to the Root if there is none.
+	Filter func(className string) bool
+
+	// Instance, if not nil, is called with each instance when its Item is
+	// closed. Instances are received in post-order; the descendants of the
+	// instance are complete, but it has not yet been added to its parent.
+	// References to instances that appear later in the document, as well as
+	// SharedString values, are resolved only after the entire document has
+	// been read. If an error is returned, then decoding stops, and the error
+	// is returned.
+	Instance func(instance *rbxfile.Instance) error
+}
+
 // Serializer implements functions that decode and encode directly between
 // byte streams and rbxfile Root structures.
 type Serializer struct {
 	Decoder Decoder
 	Encoder Encoder
+
+	// Limits bounds the resources used while deserializing. It is passed to
+	// the Document.
+	Limits rbxfile.Limits
 }
 
 // NewSerializer returns a new Serializer with a specified decoder and
@@ -54,15 +89,35 @@ func (s Serializer) Deserialize(r io.Reader) (root *rbxfile.Root, err error) {
 		return nil, errors.New("a decoder has not been not specified")
 	}
 
-	document := new(Document)
+	document := &Document{Limits: s.Limits}
 
 	if _, err = document.ReadFrom(r); err != nil {
-		return nil, errors.New("error parsing document: " + err.Error())
+		return nil, fmt.Errorf("error parsing document: %w", err)
 	}
 
 	root, err = s.Decoder.Decode(document)
 	if err != nil {
-		return nil, errors.New("error decoding data: " + err.Error())
+		return nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	return root, nil
+}
+
+// DeserializeStream is like Deserialize, but decodes the document while it
+// is read, so that the document is never held in memory in full. Instances
+// are decoded according to stream. The decoder must implement
+// StreamDecoder.
+func (s Serializer) DeserializeStream(r io.Reader, stream Stream) (root *rbxfile.Root, err error) {
+	decoder, ok := s.Decoder.(StreamDecoder)
+	if !ok {
+		return nil, errors.New("decoder does not support streaming")
+	}
+
+	document := &Document{Limits: s.Limits}
+
+	root, err = decoder.DecodeStream(document, r, stream)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data: %w", err)
 	}
 
 	return root, nil
@@ -77,11 +132,156 @@ func (s Serializer) Serialize(w io.Writer, root *rbxfile.Root) (err error) {
 
 	document, err := s.Encoder.Encode(root)
 	if err != nil {
-		return errors.New("error encoding data: " + err.Error())
+		return fmt.Errorf("error encoding data: %w", err)
 	}
 
 	if _, err = document.WriteTo(w); err != nil {
-		return errors.New("error encoding format: " + err.Error())
+		return fmt.Errorf("error encoding format: %w", err)
+	}
+
+	return nil
+}
+
+// SerializeStream is like Serialize, but writes to w while root is encoded,
+// rather than building a Document first. The output is the same. The encoder
+// must implement StreamEncoder.
+func (s Serializer) SerializeStream(w io.Writer, root *rbxfile.Root) (err error) {
+	encoder, ok := s.Encoder.(StreamEncoder)
+	if !ok {
+		return errors.New("encoder does not support streaming")
+	}
+
+	if err = encoder.EncodeStream(w, nil, root); err != nil {
+		return fmt.Errorf("error encoding data: %w", err)
+	}
+
+	return nil
+}
+
+// monitor tracks the progress of an operation that stops once its context is
+// done.
+type monitor struct {
+	ctx      context.Context
+	fn       rbxfile.ProgressFunc
+	progress rbxfile.Progress
+}
+
+func (m *monitor) report() {
+	if m.fn != nil {
+		m.fn(m.progress)
+	}
+}
+
+// monitorReader counts the bytes read from r, failing once the context of m
+// is done.
+type monitorReader struct {
+	m *monitor
+	r io.Reader
+}
+
+func (r monitorReader) Read(p []byte) (n int, err error) {
+	if err = r.m.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err = r.r.Read(p)
+	r.m.progress.Bytes += int64(n)
+	r.m.report()
+	return n, err
+}
+
+// monitorWriter counts the bytes written to w, failing once the context of m
+// is done.
+type monitorWriter struct {
+	m *monitor
+	w io.Writer
+}
+
+func (w monitorWriter) Write(p []byte) (n int, err error) {
+	if err = w.m.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err = w.w.Write(p)
+	w.m.progress.Bytes += int64(n)
+	w.m.report()
+	return n, err
+}
+
+// DeserializeContext is like Deserialize, but stops once ctx is done,
+// returning ctx.Err(). If progress is not nil, it receives the progress of
+// reading and decoding. If the decoder implements StreamDecoder, then the
+// document is decoded as it is read, and each instance is counted as it is
+// built. Otherwise, the instances are counted once the document has been
+// decoded.
+func (s Serializer) DeserializeContext(ctx context.Context, r io.Reader, progress rbxfile.ProgressFunc) (root *rbxfile.Root, err error) {
+	if s.Decoder == nil {
+		return nil, errors.New("a decoder has not been not specified")
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := &monitor{ctx: ctx, fn: progress}
+	r = monitorReader{m: m, r: r}
+	document := &Document{Limits: s.Limits}
+
+	if decoder, ok := s.Decoder.(StreamDecoder); ok {
+		root, err = decoder.DecodeStream(document, r, Stream{
+			Instance: func(*rbxfile.Instance) error {
+				m.progress.Instances++
+				m.report()
+				return ctx.Err()
+			},
+		})
+	} else if _, err = document.ReadFrom(r); err == nil {
+		if err = ctx.Err(); err == nil {
+			root, err = s.Decoder.Decode(document)
+		}
+		if err == nil {
+			root.Walk(func(*rbxfile.Instance, int) rbxfile.WalkAction {
+				m.progress.Instances++
+				return rbxfile.WalkContinue
+			}, nil)
+			m.report()
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data: %w", err)
+	}
+
+	return root, nil
+}
+
+// SerializeContext is like Serialize, but stops once ctx is done, returning
+// ctx.Err(). If progress is not nil, it receives the progress of writing. If
+// the encoder implements StreamEncoder, then the document is written while
+// root is encoded.
+func (s Serializer) SerializeContext(ctx context.Context, w io.Writer, root *rbxfile.Root, progress rbxfile.ProgressFunc) (err error) {
+	if s.Encoder == nil {
+		return errors.New("an encoder has not been not specified")
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	m := &monitor{ctx: ctx, fn: progress}
+	w = monitorWriter{m: m, w: w}
+
+	if encoder, ok := s.Encoder.(StreamEncoder); ok {
+		err = encoder.EncodeStream(w, nil, root)
+	} else {
+		var document *Document
+		if document, err = s.Encoder.Encode(root); err == nil {
+			_, err = document.WriteTo(w)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("error encoding data: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,49 @@
+//go:build go1.18
+// +build go1.18
+
+package xml
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robloxapi/rbxfile"
+	"github.com/robloxapi/rbxfile/internal/fuzzseed"
+)
+
+// Limits used while fuzzing, so that crafted input does not exhaust memory.
+var fuzzLimits = rbxfile.Limits{
+	MaxTotalBytes: 1 << 22,
+	MaxDepth:      1 << 10,
+}
+
+// addSeeds adds the seed corpus for inputs with the given extensions, along
+// with a minimal file.
+func addSeeds(f *testing.F, exts ...string) {
+	fuzzseed.Add(f, exts...)
+	f.Add([]byte(`<roblox version="4"></roblox>`))
+}
+
+func FuzzDocumentReadFrom(f *testing.F) {
+	addSeeds(f, ".rbxlx", ".rbxmx")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		doc := &Document{Limits: fuzzLimits}
+		doc.ReadFrom(bytes.NewReader(b))
+	})
+}
+
+func FuzzRobloxCodecDecode(f *testing.F) {
+	addSeeds(f, ".rbxlx", ".rbxmx")
+	// Documents without a root tag.
+	f.Add([]byte(`<!-- -->`))
+	// Malformed or missing CustomPhysics.
+	f.Add([]byte(`<roblox version="4"><Item class="Part"><Properties><PhysicalProperties name="P"></PhysicalProperties></Properties></Item></roblox>`))
+	f.Add([]byte(`<roblox version="4"><Item class="Part"><Properties><PhysicalProperties name="P"><CustomPhysics>yes</CustomPhysics></PhysicalProperties></Properties></Item></roblox>`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		doc := &Document{Limits: fuzzLimits}
+		if _, err := doc.ReadFrom(bytes.NewReader(b)); err != nil {
+			return
+		}
+		RobloxCodec{}.Decode(doc)
+	})
+}